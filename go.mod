@@ -1,10 +1,11 @@
 module todo
 
-go 1.23.2
+go 1.24
 
 require (
 	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/jmoiron/sqlx v1.4.0
+	github.com/lib/pq v1.10.9
 	github.com/stretchr/testify v1.9.0
 	modernc.org/sqlite v1.33.1
 )
@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"todo/repository"
 	"todo/task"
@@ -15,37 +20,95 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
+// Ключ контекста запроса, под которым AuthMiddleware сохраняет часовой пояс пользователя из токена.
+type locationKey struct{}
+
 type Handler struct {
-	RP repository.RepositoryProcesser
+	RP    repository.RepositoryProcesser
+	Clock task.Clock
 }
 
 // Интерфейс для работы с объектом типа обработчик (Handler).
 type HandleProcesser interface {
 	HandleTask(w http.ResponseWriter, r *http.Request)
 	HandleDate(w http.ResponseWriter, r *http.Request)
+	HandleDates(w http.ResponseWriter, r *http.Request)
+	ParseRepeatHandle(w http.ResponseWriter, r *http.Request)
 	GetTasksHandle(w http.ResponseWriter, r *http.Request)
 	GetTaskHandle(w http.ResponseWriter, r *http.Request)
 	PutTaskHandle(w http.ResponseWriter, r *http.Request)
 	DoneTaskeHandle(w http.ResponseWriter, r *http.Request)
+	SkipTaskHandle(w http.ResponseWriter, r *http.Request)
+	ExdateHandle(w http.ResponseWriter, r *http.Request)
+	HandleLists(w http.ResponseWriter, r *http.Request)
+	HandleList(w http.ResponseWriter, r *http.Request)
+	HandleListTasks(w http.ResponseWriter, r *http.Request)
 	Auth(w http.ResponseWriter, r *http.Request)
 	AuthMiddleware(next http.HandlerFunc) http.HandlerFunc
 }
 
+// Возвращает обработчик с хранилищем, заданным переменными окружения TODO_STORAGE
+// (sqlite, memory или postgres, по умолчанию sqlite) и TODO_DSN.
 func NewHandler() Handler {
-	rp, err := repository.NewRepo()
+	rp, err := repository.OpenFromEnv()
 	if err != nil {
 		panic(err)
 	}
-	return Handler{RP: rp}
+	return Handler{RP: rp, Clock: rp.Clock}
+}
+
+// Возвращает часовой пояс запроса: параметр tz, заголовок X-Timezone или пояс пользователя из токена.
+// Если пояс не указан, возвращает nil, и используется пояс сервера по умолчанию.
+func requestLocation(r *http.Request) (*time.Location, error) {
+	name := r.FormValue("tz")
+	if name == "" {
+		name = r.Header.Get("X-Timezone")
+	}
+	if name == "" {
+		loc, _ := r.Context().Value(locationKey{}).(*time.Location)
+		return loc, nil
+	}
+	return task.LoadLocation(name)
+}
+
+// Возвращает обработчик, у которого часы и репозиторий работают в часовом поясе запроса.
+func (h Handler) inLocation(r *http.Request) (Handler, error) {
+	loc, err := requestLocation(r)
+	if err != nil || loc == nil {
+		return h, err
+	}
+	return Handler{RP: h.RP.WithLocation(loc), Clock: task.InLocation(h.Clock, loc)}, nil
 }
 
 // Обработчик возвращающий следующую даты для выполненной задачи.
+// Если параметр now не указан, используется текущая дата по часам обработчика.
+// Для задачи со временем (параметр time) и правил h N, min N дата возвращается вместе со временем.
+// Параметр tz задает часовой пояс, в котором считаются now и текущий день.
 func (h Handler) HandleDate(w http.ResponseWriter, r *http.Request) {
-	task := new(task.Task)
-	task.Date = r.FormValue("date")
-	task.Repeat = r.FormValue("repeat")
-	now := r.FormValue("now")
-	nextDt, err := task.GetNextRepeatDateTest(now)
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := h.Clock.Now()
+	if nowStr := r.FormValue("now"); nowStr != "" {
+		now, err = task.ParseDateTime(nowStr, now.Location())
+		if err != nil {
+			log.Print(err)
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	date, err := task.ParseDate(r.FormValue("date"))
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t := task.Task{Date: date, Time: r.FormValue("time"), Repeat: r.FormValue("repeat")}
+	nextDt, err := t.NextDateTime(now)
 	if err != nil {
 		log.Print(err)
 		JsonErr(w, http.StatusBadRequest, err.Error())
@@ -55,6 +118,164 @@ func (h Handler) HandleDate(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(nextDt))
 }
 
+// Возвращает язык описаний правил повторения: параметр lang или заголовок Accept-Language.
+func requestLang(r *http.Request) string {
+	lang := r.FormValue("lang")
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	if strings.HasPrefix(strings.ToLower(lang), task.LangEn) {
+		return task.LangEn
+	}
+	return task.LangRu
+}
+
+// Заполняет описания правил повторения у задач.
+func describeTasks(tasks []task.Task, lang string) {
+	for i := range tasks {
+		if tasks[i].Repeat == "" {
+			continue
+		}
+		if desc, err := tasks[i].Describe(lang); err == nil {
+			tasks[i].Description = desc
+		}
+	}
+}
+
+// Сколько дат не больше возвращает HandleDates.
+const maxPreviewDates = 1000
+
+// Обработчик возвращающий серию ближайших дат повторения и описание правила в формате JSON.
+// Правило берется из задачи с указанным id либо из параметров date, time, repeat, until и count.
+// Параметр n задает количество дат (по умолчанию 10), параметры from и to - интервал дат вместо n.
+func (h Handler) HandleDates(w http.ResponseWriter, r *http.Request) {
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := h.Clock.Now()
+	if nowStr := r.FormValue("now"); nowStr != "" {
+		now, err = task.ParseDateTime(nowStr, now.Location())
+		if err != nil {
+			log.Print(err)
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	t := task.Task{
+		Date:   task.DateOf(now),
+		Time:   r.FormValue("time"),
+		Repeat: r.FormValue("repeat"),
+	}
+	if date := r.FormValue("date"); date != "" {
+		if t.Date, err = task.ParseDate(date); err != nil {
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if until := r.FormValue("until"); until != "" {
+		if t.Until, err = task.ParseDate(until); err != nil {
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if count := r.FormValue("count"); count != "" {
+		if t.Count, err = strconv.Atoi(count); err != nil {
+			JsonErr(w, http.StatusBadRequest, "wrong count")
+			return
+		}
+	}
+	if id := r.FormValue("id"); id != "" {
+		if t, err = h.RP.GetTask(id); err != nil {
+			log.Print(err)
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	desc, err := t.Describe(requestLang(r))
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var dates []string
+	if fromStr, toStr := r.FormValue("from"), r.FormValue("to"); fromStr != "" || toStr != "" {
+		from, fromErr := task.ParseDate(fromStr)
+		to, toErr := task.ParseDate(toStr)
+		if fromErr != nil || toErr != nil || to.Before(from) {
+			JsonErr(w, http.StatusBadRequest, "wrong from or to date")
+			return
+		}
+		dates, err = t.Between(from.In(now.Location()), to.In(now.Location()), maxPreviewDates)
+	} else {
+		n := 10
+		if nStr := r.FormValue("n"); nStr != "" {
+			n, err = strconv.Atoi(nStr)
+			if err != nil || n < 1 || n > maxPreviewDates {
+				JsonErr(w, http.StatusBadRequest, "wrong n")
+				return
+			}
+		}
+		dates, err = t.Upcoming(now, n)
+		if errors.Is(err, task.ErrSeriesEnded) {
+			dates, err = []string{}, nil
+		}
+	}
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if dates == nil {
+		dates = []string{}
+	}
+
+	resp, err := json.Marshal(map[string]any{
+		"description": desc,
+		"dates":       dates,
+	})
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Write(resp)
+}
+
+// Обработчик, разбирающий правило повторения из параметра text: короткий синтаксис, RRULE
+// или обычную фразу ("every last friday", "каждый понедельник").
+// Возвращает каноническое правило repeat, его запись в виде RRULE и описание.
+func (h Handler) ParseRepeatHandle(w http.ResponseWriter, r *http.Request) {
+	repeat, err := task.NormalizeRepeat(r.FormValue("text"))
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule, err := task.ParseRule(repeat)
+	if err != nil || rule.Freq == task.FreqNone {
+		JsonErr(w, http.StatusBadRequest, "empty repeat rule")
+		return
+	}
+
+	resp, err := json.Marshal(map[string]any{
+		"repeat":      repeat,
+		"rrule":       rule.RRule(),
+		"description": rule.Describe(requestLang(r)),
+	})
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Write(resp)
+}
+
 // Обработчик, поведение которого зависит от метода в r *http.Request.
 func (h Handler) HandleTask(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -128,10 +349,20 @@ func (h Handler) PostHandle(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.Unmarshal(buf.Bytes(), &newTask); err != nil {
 		log.Print(err)
+		if errors.Is(err, task.ErrInvalidDate) {
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		JsonErr(w, http.StatusBadRequest, "Ошибка десериализации JSON")
 		return
 	}
 
+	h, err = h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	id, err := h.RP.AddTask(newTask)
 	if err != nil {
 		log.Print(err)
@@ -142,17 +373,90 @@ func (h Handler) PostHandle(w http.ResponseWriter, r *http.Request) {
 	JsonResponse(w, http.StatusOK, id)
 }
 
-// Обработчик возвращающий список из 10 ближайших задач.
+// Страница списка задач в ответе /api/tasks.
+type tasksPage struct {
+	Tasks      []task.Task `json:"tasks"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Total      int         `json:"total"`
+}
+
+// Разбирает параметры страницы списка задач: limit, cursor, from, to (даты задач включительно),
+// repeat (true - только повторяющиеся, false - только разовые), sort (date или title), order (asc или desc).
+// Возвращает false, если ни один параметр не указан.
+func listQuery(r *http.Request) (repository.ListQuery, bool, error) {
+	var q repository.ListQuery
+	ok := false
+	for _, name := range []string{"limit", "cursor", "from", "to", "repeat", "sort", "order"} {
+		if r.FormValue(name) != "" {
+			ok = true
+		}
+	}
+	if !ok {
+		return q, false, nil
+	}
+
+	var err error
+	if v := r.FormValue("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit <= 0 {
+			return q, true, fmt.Errorf("неверный размер страницы %q", v)
+		}
+	}
+	q.Cursor = r.FormValue("cursor")
+	if v := r.FormValue("from"); v != "" {
+		if q.From, err = task.ParseDate(v); err != nil {
+			return q, true, err
+		}
+	}
+	if v := r.FormValue("to"); v != "" {
+		if q.To, err = task.ParseDate(v); err != nil {
+			return q, true, err
+		}
+	}
+	if v := r.FormValue("repeat"); v != "" {
+		repeat, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, true, fmt.Errorf("неверное значение repeat %q, ожидается true или false", v)
+		}
+		q.Repeat = repository.RepeatNone
+		if repeat {
+			q.Repeat = repository.RepeatOnly
+		}
+	}
+	q.Sort = r.FormValue("sort")
+	switch order := r.FormValue("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, true, fmt.Errorf("неверный порядок сортировки %q, ожидается asc или desc", order)
+	}
+	return q, true, nil
+}
+
+// Обработчик возвращающий список из 10 ближайших задач, либо страницу списка
+// с фильтрами и курсором следующей страницы, если указаны параметры listQuery.
 func (h Handler) GetTasksHandle(w http.ResponseWriter, r *http.Request) {
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	search := r.FormValue("search")
 
 	if search != "" {
 		taskSLice, err := h.RP.SearchTask(search)
 		if err != nil {
 			log.Print(err)
-			JsonErr(w, http.StatusBadRequest, err.Error())
+			var queryErr *repository.QueryError
+			if errors.As(err, &queryErr) {
+				JsonErr(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			JsonErr(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		describeTasks(taskSLice, requestLang(r))
 		respMap := make(map[string][]task.Task)
 		respMap["tasks"] = taskSLice
 		resp, err := json.Marshal(respMap)
@@ -165,11 +469,35 @@ func (h Handler) GetTasksHandle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, ok, err := listQuery(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if ok {
+		page, err := h.RP.ListTasks(q)
+		if err != nil {
+			log.Print(err)
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		describeTasks(page.Tasks, requestLang(r))
+		resp, err := json.Marshal(tasksPage{Tasks: page.Tasks, NextCursor: page.NextCursor, Total: page.Total})
+		if err != nil {
+			log.Print(err)
+			JsonErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Write(resp)
+		return
+	}
+
 	taskSLice, err := h.RP.GetTaskList()
 	if err != nil {
 		log.Print(err)
 		JsonErr(w, http.StatusBadRequest, err.Error())
 	}
+	describeTasks(taskSLice, requestLang(r))
 
 	respMap := make(map[string][]task.Task)
 	respMap["tasks"] = taskSLice
@@ -195,6 +523,9 @@ func (h Handler) GetTaskHandle(w http.ResponseWriter, r *http.Request) {
 		JsonErr(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if task.Repeat != "" {
+		task.Description, _ = task.Describe(requestLang(r))
+	}
 	resp, err := json.Marshal(task)
 	if err != nil {
 		log.Print(err)
@@ -207,21 +538,30 @@ func (h Handler) GetTaskHandle(w http.ResponseWriter, r *http.Request) {
 
 // Обработчик обновляющий задачу.
 func (h Handler) PutTaskHandle(w http.ResponseWriter, r *http.Request) {
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	var buf bytes.Buffer
-	task := task.Task{}
-	_, err := buf.ReadFrom(r.Body)
+	t := task.Task{}
+	_, err = buf.ReadFrom(r.Body)
 	if err != nil {
 		log.Print(err)
 	}
 
-	err = json.Unmarshal(buf.Bytes(), &task)
+	err = json.Unmarshal(buf.Bytes(), &t)
 	if err != nil {
 		log.Print(err)
-		JsonErr(w, http.StatusInternalServerError, err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, task.ErrInvalidDate) {
+			status = http.StatusBadRequest
+		}
+		JsonErr(w, status, err.Error())
 		return
 	}
-	err = h.RP.UpdateTask(task)
+	err = h.RP.UpdateTask(t)
 	if err != nil {
 		log.Print(err)
 		JsonErr(w, http.StatusInternalServerError, err.Error())
@@ -238,9 +578,15 @@ func (h Handler) PutTaskHandle(w http.ResponseWriter, r *http.Request) {
 
 // Обработчик выполнения задачи.
 func (h Handler) DoneTaskeHandle(w http.ResponseWriter, r *http.Request) {
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	id := r.FormValue("id")
 
-	err := h.RP.DoneTask(id)
+	err = h.RP.DoneTask(id)
 	if err != nil {
 		log.Print(err)
 		JsonErr(w, http.StatusBadRequest, "wrong id")
@@ -272,10 +618,209 @@ func (h Handler) DeleteTaskeHandle(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Обработчик пропуска текущего повторения задачи.
+func (h Handler) SkipTaskHandle(w http.ResponseWriter, r *http.Request) {
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id := r.FormValue("id")
+
+	err = h.RP.SkipTask(id)
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := struct{}{}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Print(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Обработчик исключенных дат задачи: POST исключает дату из серии повторений, DELETE возвращает ее.
+func (h Handler) ExdateHandle(w http.ResponseWriter, r *http.Request) {
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.Method != "POST" && r.Method != "DELETE" {
+		JsonErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := r.FormValue("id")
+	date, err := task.ParseDate(r.FormValue("date"))
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.Method == "POST" {
+		err = h.RP.ExcludeDate(id, date)
+	} else {
+		err = h.RP.IncludeDate(id, date)
+	}
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := struct{}{}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Print(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Возвращает код ответа для ошибки сохраненного поиска.
+func savedSearchStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrSearchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrSearchExists):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// Читает сохраненный поиск {"name": ..., "query": ...} из тела запроса.
+func readSavedSearch(r *http.Request) (repository.SavedSearch, error) {
+	var s repository.SavedSearch
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		log.Print(err)
+		return s, errors.New("Не удалось прочитать тело запроса")
+	}
+	if err := json.Unmarshal(buf.Bytes(), &s); err != nil {
+		log.Print(err)
+		return s, errors.New("Ошибка десериализации JSON")
+	}
+	return s, nil
+}
+
+// Вспомогательная функция, посылающая значение v в формате JSON.
+func writeJSON(w http.ResponseWriter, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		log.Print(err)
+		JsonErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Write(resp)
+}
+
+// Обработчик сохраненных поисков (умных списков): GET возвращает все сохраненные поиски
+// по имени, POST сохраняет новый поиск {"name": ..., "query": ...} с запросом как у /api/tasks?search=.
+func (h Handler) HandleLists(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		lists, err := h.RP.SavedSearches()
+		if err != nil {
+			log.Print(err)
+			JsonErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, map[string][]repository.SavedSearch{"lists": lists})
+	case "POST":
+		s, err := readSavedSearch(r)
+		if err != nil {
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if s, err = h.RP.AddSavedSearch(s); err != nil {
+			log.Print(err)
+			JsonErr(w, savedSearchStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, s)
+	default:
+		JsonErr(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// Обработчик сохраненного поиска с именем из пути /api/lists/{name}: GET возвращает поиск,
+// PUT заменяет его запрос на {"query": ...}, DELETE удаляет поиск.
+func (h Handler) HandleList(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case "GET":
+		s, err := h.RP.GetSavedSearch(name)
+		if err != nil {
+			log.Print(err)
+			JsonErr(w, savedSearchStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, s)
+	case "PUT":
+		s, err := readSavedSearch(r)
+		if err != nil {
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.Name = name
+		if s, err = h.RP.UpdateSavedSearch(s); err != nil {
+			log.Print(err)
+			JsonErr(w, savedSearchStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, s)
+	case "DELETE":
+		if err := h.RP.DeleteSavedSearch(name); err != nil {
+			log.Print(err)
+			JsonErr(w, savedSearchStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, struct{}{})
+	default:
+		JsonErr(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// Обработчик, возвращающий текущие результаты сохраненного поиска /api/lists/{name}/tasks
+// в том же виде, что и поиск /api/tasks?search=. Относительные даты запроса считаются в часовом поясе запроса.
+func (h Handler) HandleListTasks(w http.ResponseWriter, r *http.Request) {
+	h, err := h.inLocation(r)
+	if err != nil {
+		JsonErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	taskSLice, err := h.RP.SavedSearchTasks(r.PathValue("name"))
+	if err != nil {
+		log.Print(err)
+		var queryErr *repository.QueryError
+		switch {
+		case errors.Is(err, repository.ErrSearchNotFound):
+			JsonErr(w, http.StatusNotFound, err.Error())
+		case errors.As(err, &queryErr):
+			JsonErr(w, http.StatusBadRequest, err.Error())
+		default:
+			JsonErr(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	describeTasks(taskSLice, requestLang(r))
+	writeJSON(w, map[string][]task.Task{"tasks": taskSLice})
+}
+
 type AuthPass struct {
 	Password string `json:"password"`
+	TZ       string `json:"tz,omitempty"` // часовой пояс пользователя, сохраняется в токене
 }
 
+// Обработчик входа: проверяет пароль и выдает токен. Часовой пояс tz из запроса
+// сохраняется в токене и используется для всех запросов пользователя, где пояс не указан явно.
 func (h Handler) Auth(w http.ResponseWriter, r *http.Request) {
 	passwd := os.Getenv("TODO_PASSWORD")
 
@@ -291,10 +836,21 @@ func (h Handler) Auth(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 	}
 
+	if auth.TZ != "" {
+		if _, err := task.LoadLocation(auth.TZ); err != nil {
+			JsonErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	if auth.Password == passwd {
-		jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		claims := jwt.MapClaims{
 			"sum": sha256.Sum256([]byte(auth.Password)),
-		})
+		}
+		if auth.TZ != "" {
+			claims["tz"] = auth.TZ
+		}
+		jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 		tokenString, err := jwtToken.SignedString([]byte(passwd))
 		if err != nil {
 			log.Print(err)
@@ -350,6 +906,15 @@ func (h Handler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 				http.Error(w, "Authentification required", http.StatusUnauthorized)
 				return
 			}
+
+			// часовой пояс пользователя, указанный при входе
+			if claims, ok := token.Claims.(jwt.MapClaims); ok {
+				if name, ok := claims["tz"].(string); ok {
+					if loc, err := task.LoadLocation(name); err == nil {
+						r = r.WithContext(context.WithValue(r.Context(), locationKey{}, loc))
+					}
+				}
+			}
 		}
 		next(w, r)
 	})
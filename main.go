@@ -1,19 +1,83 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
 	"os"
 
+	"todo/repository"
 	"todo/server"
+	"todo/task"
 )
 
 func main() {
+	pending := flag.Bool("migrations", false, "вывести непримененные миграции схемы БД и выйти")
+	dryRun := flag.Bool("migrate-dry-run", false, "проверить непримененные миграции схемы БД без изменения БД и выйти")
+	flag.Parse()
+
+	if *pending || *dryRun {
+		if err := printMigrations(*dryRun); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	port := ":" + os.Getenv("TODO_PORT")
 	if port == ":" {
 		port = ":7540"
 	}
 
+	// Производственный календарь для правил с учетом рабочих дней (.ics или JSON)
+	if file := os.Getenv("TODO_HOLIDAYS"); file != "" {
+		calendar, err := task.LoadCalendar(file)
+		if err != nil {
+			panic(err)
+		}
+		task.SetCalendar(calendar)
+	}
+
+	// Часовой пояс по умолчанию, в котором определяются текущий день и время (Europe/Moscow, +03:00)
+	if tz := os.Getenv("TODO_TZ"); tz != "" {
+		loc, err := task.LoadLocation(tz)
+		if err != nil {
+			panic(err)
+		}
+		task.SetLocation(loc)
+	}
+
 	srv := server.NewSrv()
 
 	srv.Run(port)
 }
+
+// Выводит непримененные миграции схемы БД хранилища TODO_STORAGE (TODO_DSN). С dryRun миграции
+// также выполняются и откатываются, чтобы убедиться, что они применимы к этой БД.
+func printMigrations(dryRun bool) error {
+	m, err := repository.OpenMigrator(os.Getenv("TODO_STORAGE"), os.Getenv("TODO_DSN"))
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	var pending []repository.Migration
+	if dryRun {
+		pending, err = m.Migrate(true)
+	} else {
+		pending, err = m.Pending()
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Версия схемы: %d, непримененных миграций: %d\n", version, len(pending))
+	for _, m := range pending {
+		fmt.Printf("%d\t%s\n", m.Version, m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	task "todo/task"
+)
+
+// Ошибка разбора даты в поиске.
+var errSearchDate = errors.New("неизвестная дата")
+
+// Относительные даты поиска: по сегодняшнему дню возвращают первый и последний дни периода.
+// Нулевая граница - период с этой стороны не ограничен.
+var relativeDates = map[string]func(today time.Time) (time.Time, time.Time){
+	"today":              dayOffset(0),
+	"сегодня":            dayOffset(0),
+	"tomorrow":           dayOffset(1),
+	"завтра":             dayOffset(1),
+	"day after tomorrow": dayOffset(2),
+	"послезавтра":        dayOffset(2),
+	"yesterday":          dayOffset(-1),
+	"вчера":              dayOffset(-1),
+
+	"this week":           weekOffset(0),
+	"эта неделя":          weekOffset(0),
+	"на этой неделе":      weekOffset(0),
+	"next week":           weekOffset(1),
+	"следующая неделя":    weekOffset(1),
+	"на следующей неделе": weekOffset(1),
+	"last week":           weekOffset(-1),
+	"прошлая неделя":      weekOffset(-1),
+	"на прошлой неделе":   weekOffset(-1),
+
+	"this month":         monthOffset(0),
+	"этот месяц":         monthOffset(0),
+	"в этом месяце":      monthOffset(0),
+	"next month":         monthOffset(1),
+	"следующий месяц":    monthOffset(1),
+	"в следующем месяце": monthOffset(1),
+	"last month":         monthOffset(-1),
+	"прошлый месяц":      monthOffset(-1),
+	"в прошлом месяце":   monthOffset(-1),
+
+	"this year":   yearOffset(0),
+	"этот год":    yearOffset(0),
+	"в этом году": yearOffset(0),
+
+	"overdue":      overdue,
+	"просроченные": overdue,
+	"просрочено":   overdue,
+}
+
+func dayOffset(n int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		d := today.AddDate(0, 0, n)
+		return d, d
+	}
+}
+
+// Недели начинаются с понедельника.
+func weekOffset(n int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7+7*n)
+		return monday, monday.AddDate(0, 0, 6)
+	}
+}
+
+func monthOffset(n int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		first := time.Date(today.Year(), today.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+		return first, first.AddDate(0, 1, -1)
+	}
+}
+
+func yearOffset(n int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		first := time.Date(today.Year()+n, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return first, first.AddDate(1, 0, -1)
+	}
+}
+
+// Просроченные задачи - все задачи до сегодняшнего дня.
+func overdue(today time.Time) (time.Time, time.Time) {
+	return time.Time{}, today.AddDate(0, 0, -1)
+}
+
+// Форматы дат поиска и длина периода, который обозначает дата.
+var searchDateLayouts = []struct {
+	layout        string
+	years, months int
+}{
+	{"02.01.2006", 0, 0},
+	{"2006-01-02", 0, 0},
+	{"20060102", 0, 0},
+	{"01.2006", 0, 1},
+	{"2006-01", 0, 1},
+	{"2006", 1, 0},
+}
+
+// Разделители границ диапазона дат.
+var dateRangeSeparators = []string{"..", "—", "–", "-"}
+
+// Разбирает дату поиска и возвращает первый и последний дни, которые она обозначает.
+// Понимает даты 02.01.2006, 2006-01-02 и 20060102, месяцы 01.2006 и 2006-01, годы 2006,
+// диапазоны "01.10.2026-15.10.2026" и относительные даты на английском и русском
+// ("today", "завтра", "на этой неделе", "overdue"), которые отсчитываются от now.
+// Нулевая граница означает, что диапазон с этой стороны не ограничен.
+func parseDateRange(s string, now time.Time) (task.Date, task.Date, error) {
+	s = strings.TrimSpace(s)
+	if from, to, ok := parseSearchDate(s, now); ok {
+		return from, to, nil
+	}
+	for _, sep := range dateRangeSeparators {
+		for i := strings.Index(s, sep); i >= 0; {
+			from, _, okFrom := parseSearchDate(strings.TrimSpace(s[:i]), now)
+			_, to, okTo := parseSearchDate(strings.TrimSpace(s[i+len(sep):]), now)
+			if okFrom && okTo {
+				if !from.IsZero() && to.Before(from) {
+					return task.Date{}, task.Date{}, errors.New("конец диапазона дат раньше начала")
+				}
+				return from, to, nil
+			}
+			next := strings.Index(s[i+len(sep):], sep)
+			if next < 0 {
+				break
+			}
+			i += len(sep) + next
+		}
+	}
+	return task.Date{}, task.Date{}, errSearchDate
+}
+
+// Разбирает дату поиска без диапазона.
+func parseSearchDate(s string, now time.Time) (task.Date, task.Date, bool) {
+	phrase := strings.Join(strings.Fields(strings.ReplaceAll(strings.ToLower(s), "ё", "е")), " ")
+	if period, ok := relativeDates[phrase]; ok {
+		from, to := period(task.DateOf(now).Time())
+		return dateOrZero(from), dateOrZero(to), true
+	}
+	for _, v := range searchDateLayouts {
+		if d, err := time.Parse(v.layout, s); err == nil {
+			if v.years == 0 && v.months == 0 {
+				return task.DateOf(d), task.DateOf(d), true
+			}
+			return task.DateOf(d), task.DateOf(d.AddDate(v.years, v.months, -1)), true
+		}
+	}
+	return task.Date{}, task.Date{}, false
+}
+
+func dateOrZero(t time.Time) task.Date {
+	if t.IsZero() {
+		return task.Date{}
+	}
+	return task.DateOf(t)
+}
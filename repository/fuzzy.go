@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	task "todo/task"
+)
+
+// Строчные буквы с диакритикой и буквы, к которым их приводит поиск, как remove_diacritics
+// в индексах FTS5 SQLite. Туда же отнесена "ё": ее поиск приравнивает к "е".
+var diacritics = map[rune]string{
+	'a': "àáâãäåāăą", 'c': "çćĉċč", 'd': "ď", 'e': "èéêëēĕėęě", 'g': "ĝğġģ", 'h': "ĥ",
+	'i': "ìíîïĩīĭį", 'j': "ĵ", 'k': "ķ", 'l': "ĺļľ", 'n': "ñńņň", 'o': "òóôõöōŏő", 'r': "ŕŗř",
+	's': "śŝşš", 't': "ţť", 'u': "ùúûüũūŭůűų", 'w': "ŵ", 'y': "ýÿŷ", 'z': "źżž", 'е': "ё",
+}
+
+// Буква без диакритики для каждой строчной буквы из diacritics.
+var diacriticBase = func() map[rune]rune {
+	res := make(map[rune]rune)
+	for base, letters := range diacritics {
+		for _, r := range letters {
+			res[r] = base
+		}
+	}
+	return res
+}()
+
+// Возвращает букву r строчной и без диакритики.
+func lowerBase(r rune) rune {
+	r = unicode.ToLower(r)
+	if base, ok := diacriticBase[r]; ok {
+		return base
+	}
+	return r
+}
+
+// Возвращает строку строчными буквами без диакритики, как ее приводит dialect.fold.
+func sqlFold(s string) string {
+	return strings.Map(lowerBase, s)
+}
+
+// Приводит букву к виду, в котором сравниваются слова при поиске: диакритика (см. diacritics)
+// отбрасывается, а для всех алфавитов берется наименьшая буква из класса эквивалентности
+// по регистру (unicode.SimpleFold).
+func foldRune(r rune) rune {
+	r = lowerBase(r)
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		folded = min(folded, f)
+	}
+	return folded
+}
+
+// Возвращает буквы строки после foldRune.
+func foldRunes(s string) []rune {
+	res := make([]rune, 0, len(s))
+	for _, r := range s {
+		res = append(res, foldRune(r))
+	}
+	return res
+}
+
+// Слово текста задачи: байтовые границы в тексте и буквы после foldRune.
+type textWord struct {
+	start, end int
+	folded     []rune
+}
+
+// Разбивает текст на слова из букв и цифр.
+func splitWords(text string) []textWord {
+	var words []textWord
+	start := -1
+	for i, r := range text + " " {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			words = append(words, textWord{start: start, end: i, folded: foldRunes(text[start:i])})
+			start = -1
+		}
+	}
+	return words
+}
+
+// Возвращает строки, получаемые из w заменой каждой буквы r на любую из forms(r), без повторов.
+func expandRunes(w []rune, forms func(rune) []rune) []string {
+	variants := [][]rune{{}}
+	for _, r := range w {
+		next := make([][]rune, 0, len(variants))
+		for _, v := range variants {
+			for _, f := range forms(r) {
+				next = append(next, append(v[:len(v):len(v)], f))
+			}
+		}
+		variants = next
+	}
+	res := make([]string, len(variants))
+	for i, v := range variants {
+		res[i] = string(v)
+	}
+	return res
+}
+
+// Возвращает тройки букв подряд из слова w (после foldRune) и из вариантов w с переставленными
+// соседними буквами для поиска кандидатов нечеткого совпадения: при одной опечатке в слове
+// из шести и более букв хотя бы одна из троек встречается в тексте задачи. Так как foldRune
+// приравнивает "ё" к "е", для троек с "е" добавляются варианты с "ё": индекс различает эти буквы.
+func trigrams(w []rune) []string {
+	words := [][]rune{w}
+	if allowedTypos(len(w)) > 0 {
+		for i := 0; i+1 < len(w); i++ {
+			swapped := append([]rune(nil), w...)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			words = append(words, swapped)
+		}
+	}
+	withYo := func(r rune) []rune {
+		if r == foldRune('е') {
+			return []rune{r, 'ё'}
+		}
+		return []rune{r}
+	}
+
+	var res []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		for i := 0; i+3 <= len(w); i++ {
+			for _, g := range expandRunes(w[i:i+3], withYo) {
+				if !seen[g] {
+					seen[g] = true
+					res = append(res, g)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Возвращает все написания слова w (после foldRune), которые foldRune приводит к w:
+// буквы в любом регистре и с диакритикой. В словах короче трех букв троек нет, а опечатки
+// в них не допускаются, поэтому кандидаты для них ищутся по этим написаниям.
+func spellings(w []rune) []string {
+	return expandRunes(w, func(r rune) []rune {
+		var forms []rune
+		for _, v := range append([]rune{unicode.ToLower(r)}, []rune(diacritics[unicode.ToLower(r)])...) {
+			forms = append(forms, v)
+			for f := unicode.SimpleFold(v); f != v; f = unicode.SimpleFold(f) {
+				forms = append(forms, f)
+			}
+		}
+		return forms
+	})
+}
+
+// Допустимое число опечаток в слове запроса из n букв: в коротких словах опечатки не допускаются.
+func allowedTypos(n int) int {
+	switch {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	}
+	return 2
+}
+
+// Возвращает наименьшее расстояние Дамерау-Левенштейна (с перестановкой соседних букв)
+// между q и началом слова w: слово запроса ищется как префикс, как и при точном поиске.
+// Если расстояние больше limit, возвращает limit+1.
+func prefixDistance(q, w []rune, limit int) int {
+	if len(w) > len(q)+limit {
+		w = w[:len(q)+limit]
+	}
+	// prev2, prev, cur - строки таблицы расстояний для q[:i-2], q[:i-1], q[:i]
+	prev2 := make([]int, len(w)+1)
+	prev := make([]int, len(w)+1)
+	cur := make([]int, len(w)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(q); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(w); j++ {
+			cost := 1
+			if q[i-1] == w[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && q[i-1] == w[j-2] && q[i-2] == w[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	best := limit + 1
+	for j := max(0, len(q)-limit); j <= len(w); j++ {
+		best = min(best, prev[j])
+	}
+	return best
+}
+
+// Нечеткое совпадение задачи с запросом.
+type fuzzyMatch struct {
+	task     task.Task
+	distance int  // сумма опечаток по всем словам запроса
+	inTitle  bool // все слова запроса нашлись в заголовке
+}
+
+// Ищет слова и фразы запроса terms в тексте words с опечатками. Возвращает сумму опечаток,
+// границы найденных слов и признак того, что нашлись все слова запроса.
+func matchWords(terms [][][]rune, words []textWord) (int, [][2]int, bool) {
+	total := 0
+	var found [][2]int
+	for _, term := range terms {
+		best, at := -1, -1
+		// Слова фразы должны идти подряд
+		for i := 0; i+len(term) <= len(words); i++ {
+			d, ok := 0, true
+			for k, q := range term {
+				dk := prefixDistance(q, words[i+k].folded, allowedTypos(len(q)))
+				if dk > allowedTypos(len(q)) {
+					ok = false
+					break
+				}
+				d += dk
+			}
+			if ok && (best < 0 || d < best) {
+				best, at = d, i
+			}
+		}
+		if best < 0 {
+			return 0, nil, false
+		}
+		total += best
+		found = append(found, [2]int{words[at].start, words[at+len(term)-1].end})
+	}
+	return total, found, true
+}
+
+// Ищет задачи, в которых слова запроса встречаются с опечатками или в другом регистре,
+// кроме задач с id из skip. Задачи упорядочены по числу опечаток, затем совпадения
+// в заголовке идут раньше совпадений в комментарии; сниппет отмечает найденные слова.
+// Расстояние считается только для кандидатов из Store.Candidates: каждое слово запроса
+// из трех и более букв должно сохранить хотя бы одну тройку букв подряд без опечаток
+// (перестановка соседних букв учитывается, см. trigrams), а слово короче трех букв,
+// в котором опечатки не допускаются, должно встретиться в одном из написаний (см. spellings).
+func (repo *Repository) fuzzySearch(terms []searchTerm, skip map[string]bool) ([]task.Task, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	folded := make([][][]rune, len(terms))
+	var grams [][]string
+	for i, t := range terms {
+		for _, w := range splitWords(t.text) {
+			folded[i] = append(folded[i], w.folded)
+			if len(w.folded) < 3 {
+				grams = append(grams, spellings(w.folded))
+			} else {
+				grams = append(grams, trigrams(w.folded))
+			}
+		}
+		if len(folded[i]) == 0 {
+			return nil, nil
+		}
+	}
+
+	tasks, err := repo.Store.Candidates(grams)
+	if err != nil {
+		return nil, err
+	}
+	var matches []fuzzyMatch
+	for _, t := range tasks {
+		if skip[t.ID] {
+			continue
+		}
+		if d, found, ok := matchWords(folded, splitWords(t.Title)); ok {
+			snippet, _ := markTerms(t.Title, rangeTerms(t.Title, found))
+			t.Snippet = highlight(snippet)
+			matches = append(matches, fuzzyMatch{task: t, distance: d, inTitle: true})
+			continue
+		}
+		// Слова запроса могут найтись частично в заголовке, частично в комментарии
+		text := t.Title + "\n" + t.Comment
+		if d, found, ok := matchWords(folded, splitWords(text)); ok {
+			found := rangeTerms(text, found)
+			snippet, hits := markTerms(t.Title, found)
+			if hits == 0 {
+				snippet, _ = markTerms(t.Comment, found)
+			}
+			t.Snippet = highlight(snippet)
+			matches = append(matches, fuzzyMatch{task: t, distance: d})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		return a.inTitle && !b.inTitle
+	})
+	res := make([]task.Task, len(matches))
+	for i, m := range matches {
+		res[i] = m.task
+	}
+	return res, nil
+}
+
+// Возвращает участки текста ranges как слова запроса для markTerms.
+func rangeTerms(text string, ranges [][2]int) []searchTerm {
+	terms := make([]searchTerm, len(ranges))
+	for i, r := range ranges {
+		terms[i] = searchTerm{text: text[r[0]:r[1]]}
+	}
+	return terms
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	task "todo/task"
+)
+
+const (
+	DefaultPageSize = 10   // задач на странице, если размер не указан
+	MaxPageSize     = 1000 // наибольший размер страницы
+)
+
+// Поле, по которому упорядочен список задач.
+const (
+	SortDate  = "date"  // по дате и времени, как в GetTaskList
+	SortTitle = "title" // по заголовку
+)
+
+// Отбор задач по наличию правила повторения.
+const (
+	RepeatAll  = ""       // все задачи
+	RepeatOnly = "repeat" // только повторяющиеся
+	RepeatNone = "once"   // только неповторяющиеся
+)
+
+// Ошибка разбора курсора страницы.
+var ErrInvalidCursor = errors.New("неверный курсор страницы")
+
+// Параметры запроса страницы списка задач. Нулевое значение - первые DefaultPageSize задач по дате.
+type ListQuery struct {
+	Limit  int       // задач на странице, 0 - DefaultPageSize
+	Cursor string    // NextCursor предыдущей страницы, пусто - первая страница
+	From   task.Date // задачи не раньше этой даты (нулевая - без ограничения)
+	To     task.Date // задачи не позже этой даты (нулевая - без ограничения)
+	Repeat string    // RepeatAll, RepeatOnly или RepeatNone
+	Sort   string    // SortDate (по умолчанию) или SortTitle
+	Desc   bool      // по убыванию
+}
+
+// Страница списка задач.
+type TaskPage struct {
+	Tasks      []task.Task
+	NextCursor string // курсор следующей страницы, пусто для последней
+	Total      int    // число задач, подходящих под фильтры, на всех страницах
+}
+
+// Позиция в списке задач: ключ сортировки и id последней задачи предыдущей страницы.
+type Cursor struct {
+	Sort string   `json:"s"`
+	Desc bool     `json:"d,omitempty"`
+	Key  []string `json:"k"`
+	ID   int64    `json:"i"`
+}
+
+// Условия выборки задач из хранилища.
+type Filter struct {
+	From, To task.Date
+	Repeat   string
+	Sort     string
+	Desc     bool
+	After    *Cursor // задачи после этой позиции, nil - с начала списка
+	Limit    int
+}
+
+// Возвращает ключ сортировки задачи t для поля sort. Хранилища упорядочивают задачи
+// по этому ключу, а при равных ключах - по id.
+func sortKey(t task.Task, sort string) []string {
+	if sort == SortTitle {
+		return []string{t.Title}
+	}
+	return []string{t.Date.String(), t.Time}
+}
+
+// Сравнивает позиции задач в списке по возрастанию: ключи a и b, затем id.
+func compareKeys(a []string, aID int64, b []string, bID int64) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aID < bID:
+		return -1
+	case aID > bID:
+		return 1
+	}
+	return 0
+}
+
+// Возвращает курсор, указывающий на задачу t.
+func encodeCursor(t task.Task, f Filter) (string, error) {
+	id, ok := parseID(t.ID)
+	if !ok {
+		return "", fmt.Errorf("некорректный id задачи %q", t.ID)
+	}
+	data, err := json.Marshal(Cursor{Sort: f.Sort, Desc: f.Desc, Key: sortKey(t, f.Sort), ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Разбирает курсор s и проверяет, что он получен для того же порядка сортировки.
+func decodeCursor(s string, f Filter) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != f.Sort || c.Desc != f.Desc || len(c.Key) != len(sortKey(task.Task{}, f.Sort)) {
+		return nil, fmt.Errorf("%w: курсор получен для другого порядка сортировки", ErrInvalidCursor)
+	}
+	return &c, nil
+}
+
+// Возвращает страницу списка задач с фильтрами q. Следующая страница запрашивается
+// с курсором NextCursor и теми же фильтрами.
+func (repo *Repository) ListTasks(q ListQuery) (TaskPage, error) {
+	f := Filter{From: q.From, To: q.To, Repeat: q.Repeat, Sort: q.Sort, Desc: q.Desc, Limit: q.Limit}
+	if f.Sort == "" {
+		f.Sort = SortDate
+	}
+	if f.Sort != SortDate && f.Sort != SortTitle {
+		return TaskPage{}, fmt.Errorf("неизвестная сортировка %q, доступны: %s, %s", f.Sort, SortDate, SortTitle)
+	}
+	if f.Repeat != RepeatAll && f.Repeat != RepeatOnly && f.Repeat != RepeatNone {
+		return TaskPage{}, fmt.Errorf("неизвестный отбор повторяющихся задач %q", f.Repeat)
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return TaskPage{}, errors.New("размер страницы должен быть от 1 до " + strconv.Itoa(MaxPageSize))
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return TaskPage{}, errors.New("конец диапазона дат раньше начала")
+	}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, f)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		f.After = c
+	}
+
+	// Запрашиваем на одну задачу больше, чтобы узнать, есть ли следующая страница
+	limit := f.Limit
+	f.Limit++
+	tasks, total, err := repo.Store.Find(f)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	page := TaskPage{Tasks: tasks, Total: total}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.NextCursor, err = encodeCursor(page.Tasks[limit-1], f)
+		if err != nil {
+			return TaskPage{}, err
+		}
+	}
+	return page, nil
+}
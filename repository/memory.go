@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	task "todo/task"
+)
+
+// Хранилище задач в памяти для тестов и демонстраций. Данные теряются при остановке сервера.
+// Поиск находит слова запроса так же, как полнотекстовый индекс SQLite (см. termIndexes),
+// и упорядочивает задачи rankMatches.
+type memoryStore struct {
+	mu       sync.RWMutex
+	tasks    map[int64]task.Task
+	lastID   int64
+	searches map[string]string // запросы сохраненных поисков по имени
+}
+
+func init() {
+	Register("memory", func(string) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+}
+
+// Возвращает репозиторий с пустым хранилищем в памяти и часами clock
+// (nil - системные часы).
+func NewMemoryRepo(clock task.Clock) *Repository {
+	if clock == nil {
+		clock = task.SystemClock
+	}
+	return &Repository{Store: NewMemoryStore(), Clock: clock}
+}
+
+// Возвращает пустое хранилище задач в памяти.
+func NewMemoryStore() Store {
+	return &memoryStore{tasks: make(map[int64]task.Task), searches: make(map[string]string)}
+}
+
+// Возвращает копию задачи, не разделяющую с ней срез исключенных дат.
+func cloneTask(t task.Task) task.Task {
+	t.Exclude = append([]task.Date(nil), t.Exclude...)
+	task.SortDates(t.Exclude)
+	if len(t.Exclude) == 0 {
+		t.Exclude = nil
+	}
+	return t
+}
+
+// Приводит задачу к виду, в котором ее вернуло бы SQL-хранилище.
+func storedTask(t task.Task) task.Task {
+	t = cloneTask(t)
+	t.Description, t.Snippet = "", ""
+	if t.Repeat == "" {
+		t.Start, t.Until, t.Count, t.Done, t.Missed, t.Relative, t.Exclude = task.Date{}, task.Date{}, 0, 0, "", "", nil
+	}
+	return t
+}
+
+func parseID(id string) (int64, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return n, err == nil
+}
+
+func (s *memoryStore) Get(id string) (task.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n, ok := parseID(id)
+	if !ok {
+		return task.Task{}, errNoRows
+	}
+	t, ok := s.tasks[n]
+	if !ok {
+		return task.Task{}, errNoRows
+	}
+	return cloneTask(t), nil
+}
+
+// Возвращает задачи, для которых match возвращает true, упорядоченные по дате и времени.
+func (s *memoryStore) filter(match func(t task.Task) bool) []task.Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := []task.Task{}
+	for _, t := range s.tasks {
+		if match(t) {
+			result = append(result, cloneTask(t))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		ai, _ := parseID(a.ID)
+		bi, _ := parseID(b.ID)
+		return ai < bi
+	})
+	return result
+}
+
+func (s *memoryStore) List(limit int) ([]task.Task, error) {
+	result := s.filter(func(task.Task) bool { return true })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (s *memoryStore) ListByDate(date task.Date) ([]task.Task, error) {
+	return s.filter(func(t task.Task) bool { return t.Date.Equal(date) }), nil
+}
+
+func (s *memoryStore) Search(text string) ([]task.Task, error) {
+	terms := parseSearch(text)
+	tasks := s.filter(func(t task.Task) bool { return matchTerms(t, terms) })
+	rankMatches(tasks, terms)
+	return tasks, nil
+}
+
+func (s *memoryStore) Candidates(grams [][]string) ([]task.Task, error) {
+	return s.filter(func(t task.Task) bool {
+		for _, word := range grams {
+			found := false
+			for _, g := range word {
+				if foldContains(t.Title, g) || foldContains(t.Comment, g) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+func (s *memoryStore) Find(f Filter) ([]task.Task, int, error) {
+	tasks := s.filter(func(t task.Task) bool {
+		switch {
+		case !f.From.IsZero() && t.Date.Before(f.From), !f.To.IsZero() && t.Date.After(f.To):
+			return false
+		case f.Repeat == RepeatOnly && t.Repeat == "", f.Repeat == RepeatNone && t.Repeat != "":
+			return false
+		}
+		return true
+	})
+	total := len(tasks)
+
+	// Сравнивает позицию задачи t с позицией key, id в порядке сортировки f
+	compare := func(t task.Task, key []string, id int64) int {
+		n, _ := parseID(t.ID)
+		c := compareKeys(sortKey(t, f.Sort), n, key, id)
+		if f.Desc {
+			return -c
+		}
+		return c
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		n, _ := parseID(tasks[j].ID)
+		return compare(tasks[i], sortKey(tasks[j], f.Sort), n) < 0
+	})
+
+	result := []task.Task{}
+	for _, t := range tasks {
+		if len(result) == f.Limit {
+			break
+		}
+		if f.After == nil || compare(t, f.After.Key, f.After.ID) > 0 {
+			result = append(result, t)
+		}
+	}
+	return result, total, nil
+}
+
+func (s *memoryStore) Select(q *Query) ([]task.Task, error) {
+	return s.filter(q.Match), nil
+}
+
+func (s *memoryStore) Insert(t task.Task, extra ...task.Task) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.insert(t)
+	for _, e := range extra {
+		s.insert(e)
+	}
+	return id, nil
+}
+
+func (s *memoryStore) insert(t task.Task) string {
+	s.lastID++
+	t.ID = strconv.FormatInt(s.lastID, 10)
+	s.tasks[s.lastID] = storedTask(t)
+	return t.ID
+}
+
+func (s *memoryStore) Update(t task.Task, extra ...task.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := parseID(t.ID)
+	if !ok {
+		return errNoRows
+	}
+	if _, ok := s.tasks[n]; !ok {
+		return errNoRows
+	}
+	s.tasks[n] = storedTask(t)
+	for _, e := range extra {
+		s.insert(e)
+	}
+	return nil
+}
+
+func (s *memoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := parseID(id)
+	if !ok {
+		return errNoRows
+	}
+	if _, ok := s.tasks[n]; !ok {
+		return errNoRows
+	}
+	delete(s.tasks, n)
+	return nil
+}
+
+func (s *memoryStore) Searches() ([]SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]SavedSearch, 0, len(s.searches))
+	for name, query := range s.searches {
+		res = append(res, SavedSearch{Name: name, Query: query})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+func (s *memoryStore) GetSearch(name string) (SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	query, ok := s.searches[name]
+	if !ok {
+		return SavedSearch{Name: name}, errNoRows
+	}
+	return SavedSearch{Name: name, Query: query}, nil
+}
+
+func (s *memoryStore) InsertSearch(v SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.searches[v.Name]; ok {
+		return ErrSearchExists
+	}
+	s.searches[v.Name] = v.Query
+	return nil
+}
+
+func (s *memoryStore) UpdateSearch(v SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.searches[v.Name]; !ok {
+		return errNoRows
+	}
+	s.searches[v.Name] = v.Query
+	return nil
+}
+
+func (s *memoryStore) DeleteSearch(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.searches[name]; !ok {
+		return errNoRows
+	}
+	delete(s.searches, name)
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
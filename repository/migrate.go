@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Миграция схемы БД. Миграции применяются по возрастанию версий, каждая в своей транзакции,
+// а номер примененной версии записывается в таблицу schema_version.
+// Шаги миграций идемпотентны, поэтому базы, созданные до появления schema_version,
+// обновляются с версии 0 без потери данных.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// Миграции схемы SQLite в порядке применения. Новые миграции добавляются только в конец списка.
+var sqliteMigrations = []Migration{
+	{1, "таблица задач scheduler", execSQL(
+		"CREATE TABLE IF NOT EXISTS scheduler (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT,  title TEXT, comment TEXT, repeat TEXT)",
+	)},
+	{2, "состояние серий повторений repeat_state", func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE IF NOT EXISTS repeat_state (task_id INTEGER PRIMARY KEY, start TEXT, until TEXT, count INTEGER, done INTEGER, missed TEXT)")
+		if err != nil {
+			return err
+		}
+		return addColumn(tx, "repeat_state", "missed", "TEXT")
+	}},
+	{3, "режим отсчета повторений repeat_state.relative", func(tx *sql.Tx) error {
+		return addColumn(tx, "repeat_state", "relative", "TEXT")
+	}},
+	{4, "исключенные даты repeat_exdate", execSQL(
+		"CREATE TABLE IF NOT EXISTS repeat_exdate (task_id INTEGER, date TEXT, PRIMARY KEY (task_id, date))",
+	)},
+	{5, "время задач task_time", execSQL(
+		"CREATE TABLE IF NOT EXISTS task_time (task_id INTEGER PRIMARY KEY, time TEXT)",
+	)},
+	{6, "индекс по дате задачи", execSQL(
+		"CREATE INDEX IF NOT EXISTS scheduler_date ON scheduler (date)",
+	)},
+	// Индекс хранит только слова заголовков и комментариев, текст читается из scheduler.
+	// Триггеры обновляют индекс при любом изменении scheduler, rebuild заполняет его для существующих задач.
+	{7, "полнотекстовый индекс scheduler_fts", execSQL(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(title, comment,
+			content='scheduler', content_rowid='id', tokenize='unicode61 remove_diacritics 2')`,
+		`CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+			INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+			INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE ON scheduler BEGIN
+			INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+			INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+		END`,
+		"INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild')",
+	)},
+	// Индекс троек букв для поиска кандидатов нечеткого поиска, обновляется так же, как scheduler_fts.
+	{8, "индекс троек букв scheduler_trigram", execSQL(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_trigram USING fts5(title, comment,
+			content='scheduler', content_rowid='id', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS scheduler_trigram_insert AFTER INSERT ON scheduler BEGIN
+			INSERT INTO scheduler_trigram (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS scheduler_trigram_delete AFTER DELETE ON scheduler BEGIN
+			INSERT INTO scheduler_trigram (scheduler_trigram, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS scheduler_trigram_update AFTER UPDATE ON scheduler BEGIN
+			INSERT INTO scheduler_trigram (scheduler_trigram, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+			INSERT INTO scheduler_trigram (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+		END`,
+		"INSERT INTO scheduler_trigram (scheduler_trigram) VALUES ('rebuild')",
+	)},
+	{9, "сохраненные поиски saved_search", execSQL(
+		"CREATE TABLE IF NOT EXISTS saved_search (name TEXT PRIMARY KEY, query TEXT)",
+	)},
+	// Тройки букв без диакритики, как в scheduler_fts: "cafe" находит "Café" и с опечаткой.
+	// Триггеры пишут в таблицу по имени, поэтому пересоздается только она сама.
+	{10, "индекс троек букв scheduler_trigram без диакритики", execSQL(
+		"DROP TABLE IF EXISTS scheduler_trigram",
+		`CREATE VIRTUAL TABLE scheduler_trigram USING fts5(title, comment,
+			content='scheduler', content_rowid='id', tokenize='trigram remove_diacritics 1')`,
+		"INSERT INTO scheduler_trigram (scheduler_trigram) VALUES ('rebuild')",
+	)},
+}
+
+// Возвращает шаг миграции, выполняющий запросы по порядку.
+func execSQL(queries ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, q := range queries {
+			if _, err := tx.Exec(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// SQL-хранилище: диалект и функция, дополняющая строку подключения (например, путем к файлу по умолчанию).
+type sqlBackend struct {
+	d   dialect
+	dsn func(dsn string) string
+}
+
+// SQL-хранилища по именам, для которых доступны миграции схемы (см. OpenMigrator).
+var sqlBackends = make(map[string]sqlBackend)
+
+// Регистрирует SQL-хранилище name с диалектом d как хранилище задач и как схему для OpenMigrator.
+// Вызывается из init файлов хранилищ.
+func registerSQL(name string, d dialect, dsn func(string) string) {
+	backendsMu.Lock()
+	sqlBackends[name] = sqlBackend{d: d, dsn: dsn}
+	backendsMu.Unlock()
+
+	Register(name, func(s string) (Store, error) {
+		return openSQLStore(d, dsn(s))
+	})
+}
+
+// Доступ к схеме БД SQL-хранилища без применения миграций: в отличие от Open,
+// позволяет посмотреть и проверить непримененные миграции до их применения.
+type Migrator struct {
+	db *sql.DB
+	d  dialect
+}
+
+// Открывает БД хранилища name (sqlite, postgres) со строкой подключения dsn, не применяя миграции.
+// Пустое name - хранилище по умолчанию. У хранилищ без схемы БД (memory) миграций нет.
+func OpenMigrator(name, dsn string) (*Migrator, error) {
+	if name == "" {
+		name = DefaultBackend
+	}
+	backendsMu.RLock()
+	b, ok := sqlBackends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("у хранилища %q нет схемы БД и миграций", name)
+	}
+
+	db, err := sql.Open(b.d.driver, b.dsn(dsn))
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Migrator{db: db, d: b.d}, nil
+}
+
+// Возвращает все миграции схемы хранилища в порядке применения.
+func (m *Migrator) Migrations() []Migration {
+	return m.d.migrations
+}
+
+// Возвращает номер последней примененной миграции (0 для новой базы).
+func (m *Migrator) Version() (int, error) {
+	return m.d.schemaVersion(m.db)
+}
+
+// Возвращает миграции, еще не примененные к БД.
+func (m *Migrator) Pending() ([]Migration, error) {
+	return m.d.pendingMigrations(m.db)
+}
+
+// Применяет непримененные миграции и возвращает их список, с dryRun - проверяет их
+// и откатывает (см. Migrate).
+func (m *Migrator) Migrate(dryRun bool) ([]Migration, error) {
+	return m.d.migrate(m.db, dryRun)
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Возвращает номер последней примененной миграции БД SQLite
+// (0 для новой базы или базы без schema_version).
+func SchemaVersion(db *sql.DB) (int, error) {
+	return sqliteDialect.schemaVersion(db)
+}
+
+// Возвращает миграции, еще не примененные к БД SQLite.
+func PendingMigrations(db *sql.DB) ([]Migration, error) {
+	return sqliteDialect.pendingMigrations(db)
+}
+
+// Применяет к БД SQLite непримененные миграции и возвращает их список. В режиме dryRun все миграции
+// выполняются в одной транзакции, которая затем откатывается: так проверяется, что они применимы,
+// но БД не меняется.
+func Migrate(db *sql.DB, dryRun bool) ([]Migration, error) {
+	return sqliteDialect.migrate(db, dryRun)
+}
+
+func (d dialect) schemaVersion(db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRow(d.rebind(d.tableExists), "schema_version").Scan(&n)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	var version int
+	err = db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+func (d dialect) pendingMigrations(db *sql.DB) ([]Migration, error) {
+	version, err := d.schemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range d.migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func (d dialect) migrate(db *sql.DB, dryRun bool) ([]Migration, error) {
+	pending, err := d.pendingMigrations(db)
+	if err != nil || len(pending) == 0 {
+		return pending, err
+	}
+
+	if dryRun {
+		tx, err := db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		for _, m := range pending {
+			if err := d.applyMigration(tx, m); err != nil {
+				return nil, fmt.Errorf("миграция %d (%s): %w", m.Version, m.Name, err)
+			}
+		}
+		return pending, nil
+	}
+
+	for i, m := range pending {
+		tx, err := db.Begin()
+		if err != nil {
+			return pending[:i], err
+		}
+		if err = d.applyMigration(tx, m); err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			return pending[:i], fmt.Errorf("миграция %d (%s): %w", m.Version, m.Name, err)
+		}
+		log.Printf("применена миграция %d: %s", m.Version, m.Name)
+	}
+	return pending, nil
+}
+
+// Выполняет миграцию и записывает ее версию в schema_version.
+func (d dialect) applyMigration(tx *sql.Tx, m Migration) error {
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+	_, err := tx.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY, name TEXT, applied TEXT)")
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(d.rebind("INSERT INTO schema_version (version, name, applied) VALUES (?, ?, ?)"),
+		m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// Добавляет колонку в таблицу SQLite, если ее еще нет (для баз, созданных предыдущими версиями).
+func addColumn(tx *sql.Tx, table, column, def string) error {
+	var n int
+	err := tx.QueryRow("SELECT count(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
+	return err
+}
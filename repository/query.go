@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	task "todo/task"
+)
+
+// Поисковый запрос /api/tasks?search=. Слова и фразы в кавычках ищутся в заголовке и комментарии,
+// поля уточняют условие:
+//
+//	title:слово, comment:"фраза"  - слово или фраза в заголовке или комментарии
+//	repeat:any|none|d|w|m|y|h|min - задачи с правилом повторения (любым, без него, с данной частотой)
+//	before:дата, after:дата, on:дата - задачи раньше даты, позже даты, на дату (см. parseDateRange)
+//
+// Условия подряд объединяются через AND, OR и NOT (заглавными буквами) объединяют и отрицают
+// условия, скобки группируют их. NOT сильнее AND, AND сильнее OR.
+type Query struct {
+	text string
+	root queryNode
+}
+
+// Ошибка разбора поискового запроса. Pos - номер символа запроса, начиная с 1.
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("ошибка в запросе (символ %d): %s", e.Pos, e.Msg)
+}
+
+// Узел дерева запроса.
+type queryNode interface {
+	match(t task.Task) bool
+}
+
+type andNode struct{ left, right queryNode }
+
+type orNode struct{ left, right queryNode }
+
+type notNode struct{ node queryNode }
+
+// Слово или фраза в поле field (title, comment) или, если field пустое, в любом из них.
+type textNode struct {
+	field string
+	term  searchTerm
+}
+
+// Отбор по правилу повторения: any, none или частота (d, w, m, y, h, min).
+type repeatNode struct{ kind string }
+
+// Отбор по дате: before - раньше from, after - позже to, on - с from по to включительно.
+type dateNode struct {
+	op       string
+	from, to task.Date
+}
+
+func (n andNode) match(t task.Task) bool { return n.left.match(t) && n.right.match(t) }
+
+func (n orNode) match(t task.Task) bool { return n.left.match(t) || n.right.match(t) }
+
+func (n notNode) match(t task.Task) bool { return !n.node.match(t) }
+
+func (n textNode) match(t task.Task) bool {
+	switch n.field {
+	case "title":
+		return containsTerm(t.Title, n.term)
+	case "comment":
+		return containsTerm(t.Comment, n.term)
+	}
+	return containsTerm(t.Title, n.term) || containsTerm(t.Comment, n.term)
+}
+
+func (n repeatNode) match(t task.Task) bool {
+	switch n.kind {
+	case "any":
+		return t.Repeat != ""
+	case "none":
+		return t.Repeat == ""
+	}
+	rule, err := task.ParseRule(t.Repeat)
+	return err == nil && rule.Freq == repeatFreqs[n.kind]
+}
+
+func (n dateNode) match(t task.Task) bool {
+	switch n.op {
+	case "before":
+		return t.Date.Before(n.from)
+	case "after":
+		return t.Date.After(n.to)
+	}
+	return !t.Date.Before(n.from) && !t.Date.After(n.to)
+}
+
+// Частоты повторения в условии repeat: и их названия в RRULE.
+var (
+	repeatFreqs = map[string]task.Freq{
+		"d": task.FreqDaily, "w": task.FreqWeekly, "m": task.FreqMonthly, "y": task.FreqYearly,
+		"h": task.FreqHourly, "min": task.FreqMinutely,
+	}
+	repeatRRules = map[string]string{
+		"d": "DAILY", "w": "WEEKLY", "m": "MONTHLY", "y": "YEARLY", "h": "HOURLY", "min": "MINUTELY",
+	}
+)
+
+// Разбирает поисковый запрос text. Относительные даты в условиях before:, after: и on:
+// ("today", "на этой неделе") отсчитываются от now.
+func ParseQuery(text string, now time.Time) (*Query, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		// В запросе нет ни одного слова: под него не подходит ни одна задача
+		return &Query{text: text}, nil
+	}
+	p := &queryParser{tokens: tokens, now: now}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &QueryError{tok.pos, fmt.Sprintf("неожиданное %q", tok.text)}
+	}
+	return &Query{text: text, root: root}, nil
+}
+
+// Сообщает, подходит ли задача t под запрос.
+func (q *Query) Match(t task.Task) bool {
+	return q.root != nil && q.root.match(t)
+}
+
+func (q *Query) String() string {
+	return q.text
+}
+
+// Сообщает, состоит ли запрос только из слов и фраз без полей и операторов.
+// Такой запрос ищется по полнотекстовому индексу с упорядочиванием по релевантности.
+func (q *Query) plain() bool {
+	var walk func(n queryNode) bool
+	walk = func(n queryNode) bool {
+		switch n := n.(type) {
+		case andNode:
+			return walk(n.left) && walk(n.right)
+		case textNode:
+			return n.field == ""
+		}
+		return false
+	}
+	return walk(q.root)
+}
+
+// Возвращает слова и фразы запроса, которые должны встретиться в задаче (не под NOT).
+// По ним строятся сниппеты и порядок найденных задач.
+func (q *Query) terms() []searchTerm {
+	var terms []searchTerm
+	var walk func(n queryNode)
+	walk = func(n queryNode) {
+		switch n := n.(type) {
+		case andNode:
+			walk(n.left)
+			walk(n.right)
+		case orNode:
+			walk(n.left)
+			walk(n.right)
+		case textNode:
+			terms = append(terms, n.term)
+		}
+	}
+	walk(q.root)
+	return terms
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField // поле со значением: title:слово
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type queryToken struct {
+	kind   tokenKind
+	text   string // слово, фраза или значение поля
+	field  string
+	phrase bool // значение в кавычках
+	pos    int
+}
+
+// Поля запроса.
+var queryFields = map[string]bool{"title": true, "comment": true, "repeat": true, "before": true, "after": true, "on": true}
+
+// Разбивает запрос на слова, фразы, поля, скобки и операторы.
+func lexQuery(text string) ([]queryToken, error) {
+	var tokens []queryToken
+	pos := func(i int) int { return utf8.RuneCountInString(text[:i]) + 1 }
+	isDelim := func(r rune) bool { return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' }
+
+	// Читает фразу в кавычках с позиции i и возвращает ее и позицию после закрывающей кавычки
+	phrase := func(i int) (string, int, error) {
+		end := strings.IndexByte(text[i+1:], '"')
+		if end < 0 {
+			return "", 0, &QueryError{pos(i), "не закрыта кавычка"}
+		}
+		return text[i+1 : i+1+end], i + end + 2, nil
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokLParen, text: "(", pos: pos(i)})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokRParen, text: ")", pos: pos(i)})
+			i++
+		case r == '"':
+			s, next, err := phrase(i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: tokPhrase, text: s, phrase: true, pos: pos(i)})
+			i = next
+		default:
+			start := i
+			end := strings.IndexFunc(text[i:], isDelim)
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += i
+			}
+			word := text[start:end]
+			i = end
+
+			field, value, ok := strings.Cut(word, ":")
+			if ok && queryFields[strings.ToLower(field)] {
+				field = strings.ToLower(field)
+				quoted := false
+				if value == "" && i < len(text) && text[i] == '"' {
+					s, next, err := phrase(i)
+					if err != nil {
+						return nil, err
+					}
+					value, i, quoted = s, next, true
+				}
+				if strings.TrimSpace(value) == "" {
+					return nil, &QueryError{pos(start), fmt.Sprintf("не указано значение поля %s:", field)}
+				}
+				tokens = append(tokens, queryToken{kind: tokField, field: field, text: value, phrase: quoted, pos: pos(start)})
+				continue
+			}
+			if !hasWordChars(word) {
+				// Слова из одних знаков препинания не ищутся
+				continue
+			}
+
+			kind := tokWord
+			switch word {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: word, pos: pos(start)})
+		}
+	}
+	return append(tokens, queryToken{kind: tokEOF, pos: utf8.RuneCountInString(text) + 1}), nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	i      int
+	now    time.Time
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.i]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+// or = and {OR and}
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// and = not {[AND] not}
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokPhrase, tokField, tokLParen, tokNot:
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+// not = NOT not | атом
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parseAtom()
+}
+
+// атом = ( or ) | слово | "фраза" | поле:значение
+func (p *queryParser) parseAtom() (queryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &QueryError{tok.pos, "не закрыта скобка"}
+		}
+		return node, nil
+	case tokWord, tokPhrase:
+		term, err := queryTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		return textNode{term: term}, nil
+	case tokField:
+		return fieldNode(tok, p.now)
+	case tokEOF:
+		return nil, &QueryError{tok.pos, "запрос неожиданно закончился, ожидается слово, фраза или поле"}
+	}
+	return nil, &QueryError{tok.pos, fmt.Sprintf("неожиданное %q, ожидается слово, фраза или поле", tok.text)}
+}
+
+// Возвращает слово или фразу токена. Звездочка на конце слова отбрасывается:
+// слова и так ищутся как префиксы.
+func queryTerm(tok queryToken) (searchTerm, error) {
+	text := strings.TrimSpace(tok.text)
+	if !tok.phrase {
+		text = strings.TrimRight(text, "*")
+	}
+	if !hasWordChars(text) {
+		return searchTerm{}, &QueryError{tok.pos, fmt.Sprintf("в %q нет ни букв, ни цифр", tok.text)}
+	}
+	return searchTerm{text: text, phrase: tok.phrase}, nil
+}
+
+// Возвращает условие для поля запроса. Относительные даты отсчитываются от now.
+func fieldNode(tok queryToken, now time.Time) (queryNode, error) {
+	switch tok.field {
+	case "title", "comment":
+		term, err := queryTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		return textNode{field: tok.field, term: term}, nil
+	case "repeat":
+		kind := strings.ToLower(tok.text)
+		if _, ok := repeatFreqs[kind]; !ok && kind != "any" && kind != "none" {
+			return nil, &QueryError{tok.pos, fmt.Sprintf("неверное значение repeat:%s, ожидается any, none, d, w, m, y, h или min", tok.text)}
+		}
+		return repeatNode{kind}, nil
+	}
+	from, to, err := parseDateRange(tok.text, now)
+	if errors.Is(err, errSearchDate) {
+		return nil, &QueryError{tok.pos, fmt.Sprintf("неверная дата %s:%s, ожидается дата (02.01.2006, 2006-01-02, 20060102), "+
+			"месяц (01.2006), год (2006), диапазон (01.10.2026-15.10.2026) или today, сегодня, на этой неделе, overdue", tok.field, tok.text)}
+	}
+	if err != nil {
+		return nil, &QueryError{tok.pos, fmt.Sprintf("неверная дата %s:%s: %v", tok.field, tok.text, err)}
+	}
+	return dateNode{op: tok.field, from: from, to: to}, nil
+}
@@ -1,184 +1,153 @@
 package repository
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
-	"strconv"
 	"time"
 
 	task "todo/task"
-
-	_ "modernc.org/sqlite"
 )
 
 const (
 	ErrNoId     = "Не указан идентификатор"
 	ErrNotFound = "Задача не найдена"
-	DateFormat  = "20060102"
 )
 
+// Репозиторий задач: логика повторений и переноса дат поверх хранилища Store.
 type Repository struct {
-	Repo *sql.DB
+	Store Store
+	Clock task.Clock
 }
 
 // Интерфейс для работы с репозиторием
 type RepositoryProcesser interface {
 	AddTask(task task.Task) (string, error)
 	GetTaskList() ([]task.Task, error)
+	ListTasks(q ListQuery) (TaskPage, error)
 	GetTask(id string) (task.Task, error)
 	UpdateTask(task task.Task) error
 	DoneTask(id string) error
 	DeleteTask(id string) error
 	SearchTask(search string) ([]task.Task, error)
+	ExcludeDate(id string, date task.Date) error
+	IncludeDate(id string, date task.Date) error
+	SkipTask(id string) error
+	SavedSearches() ([]SavedSearch, error)
+	GetSavedSearch(name string) (SavedSearch, error)
+	AddSavedSearch(s SavedSearch) (SavedSearch, error)
+	UpdateSavedSearch(s SavedSearch) (SavedSearch, error)
+	DeleteSavedSearch(name string) error
+	SavedSearchTasks(name string) ([]task.Task, error)
+	WithLocation(loc *time.Location) RepositoryProcesser
 }
 
-// Создает (в случае необходимости) и открывает доступ к БД. Возвращает ссылку на объект типа Repository.
+// Создает (в случае необходимости) и открывает доступ к БД SQLite из файла TODO_DFILE.
+// Возвращает ссылку на объект типа Repository.
 func NewRepo() (*Repository, error) {
-
-	dbFile := os.Getenv("TODO_DFILE")
-	if dbFile == "" {
-		dbFile = dbCheck()
-	}
-
-	fmt.Println(dbFile)
-
-	repo := Repository{}
-
-	db, err := sql.Open("sqlite", dbFile)
-	if err != nil {
-		panic(err)
-	}
-
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS scheduler (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT,  title TEXT, comment TEXT, repeat TEXT)")
-	if err != nil {
-		panic(err)
-	}
-	repo.Repo = db
-	if err = db.Ping(); err != nil {
-		panic(err)
-	}
-	return &repo, nil
+	return Open("sqlite", "")
 }
 
-// Вспомогательная функция, проверяющая наличие БД в месте запуска программы.
-func dbCheck() string {
-	appPath, err := os.Executable()
-	if err != nil {
-		log.Fatal(err)
+// Возвращает пропущенные повторения задачи t на даты dates как отдельные неповторяющиеся задачи.
+func overdueTasks(t task.Task, dates []string) ([]task.Task, error) {
+	var res []task.Task
+	for _, v := range dates {
+		date, err := task.ParseDate(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, task.Task{Date: date, Time: t.Time, Title: t.Title, Comment: t.Comment})
 	}
-	fmt.Println(appPath)
-	dbFile := filepath.Join(filepath.Dir(appPath), "scheduler.db")
-	_, err = os.Stat(dbFile)
+	return res, nil
+}
 
-	if err != nil {
-		os.Create("scheduler.db")
+// Возвращает репозиторий, который считает текущий день и время в часовом поясе loc.
+// Хранилище у копии общее с исходным репозиторием.
+func (repo *Repository) WithLocation(loc *time.Location) RepositoryProcesser {
+	if loc == nil {
+		return repo
 	}
+	r := *repo
+	r.Clock = task.InLocation(repo.Clock, loc)
+	return &r
+}
 
-	return dbFile
+// Возвращает текущий день по часам репозитория.
+func (repo *Repository) today() task.Date {
+	return task.DateOf(repo.Clock.Now())
 }
 
 // Добавляет задачу в БД.
 func (repo *Repository) AddTask(task task.Task) (string, error) {
-	nextDate, err := task.GetNextRepeatDate()
-	if err != nil {
-		return "", err
-	}
-
 	if task.Title == "" {
 		return "", errors.New("no title")
 	}
 
-	if task.Date == "" {
-		task.Date = time.Now().Format(DateFormat) // Присваиваем текущую дату
+	today := repo.today()
+	if task.Date.IsZero() {
+		task.Date = today // Присваиваем текущую дату
 	}
 
-	date, err := time.Parse(DateFormat, task.Date)
-	if err != nil {
+	if err := task.Normalize(); err != nil {
+		return "", err
+	}
+	if _, err := task.Rule(); err != nil {
 		return "", err
 	}
-	// Здесь можно проверять, если дата уже прошла
-	if date.Before(time.Now().Truncate(24 * time.Hour)) {
-		switch task.Repeat {
-		case "":
-			date = time.Now()
-		default:
-			nextDateParsed, err := time.Parse(DateFormat, nextDate)
-			if err != nil {
-				return "", err
-			}
-			date = nextDateParsed // Иначе используем следующую дату
+
+	// Серия повторений отсчитывается от даты, указанной пользователем
+	task.Start, task.Done = task.Date, 0
+
+	// Если дата уже прошла, переносим задачу на сегодня или на дату по правилу повторения
+	var overdue []string
+	if task.Date.Before(today) {
+		next, dates, err := task.Reschedule(repo.Clock.Now())
+		if err != nil {
+			return "", err
 		}
+		task.MoveTo(next)
+		overdue = dates
 	}
-	task.Date = date.Format(DateFormat) // Устанавливаем отформатированную дату
 
-	res, err := repo.Repo.Exec("INSERT INTO scheduler (date, title, comment, repeat) VALUES (:date, :title, :comment, :repeat)",
-		sql.Named("date", task.Date),
-		sql.Named("title", task.Title),
-		sql.Named("comment", task.Comment),
-		sql.Named("repeat", task.Repeat))
+	extra, err := overdueTasks(task, overdue)
 	if err != nil {
 		return "", err
 	}
-
-	id, _ := res.LastInsertId()
-	strid := strconv.Itoa(int(id))
-	return strid, nil
+	return repo.Store.Insert(task, extra...)
 }
 
 // Возвращает список (срез) 10 ближайших по дате задач.
 func (repo *Repository) GetTaskList() ([]task.Task, error) {
-	result := []task.Task{}
-
-	rows, err := repo.Repo.Query("SELECT * FROM scheduler ORDER BY date LIMIT 10")
-
+	result, err := repo.Store.List(10)
 	if err != nil {
-		return result, err
-	}
-
-	for rows.Next() {
-		task := task.Task{}
-		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
-			fmt.Println(err)
-			return result, err
-		}
-		result = append(result, task)
-	}
-
-	if err := rows.Err(); err != nil {
 		fmt.Println(err)
-		return result, err
 	}
-
-	return result, nil
+	return result, err
 }
 
 // Возвращает задачу по id в виде структуры типа Task.
 func (repo *Repository) GetTask(id string) (task.Task, error) {
-	task := task.Task{}
 	if id == "" {
-		return task, fmt.Errorf(ErrNoId)
+		return task.Task{}, fmt.Errorf(ErrNoId)
 	}
-	row := repo.Repo.QueryRow("SELECT id, date, title, comment, repeat FROM scheduler WHERE id = :id", sql.Named("id", id))
-	err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	t, err := repo.Store.Get(id)
 	if err != nil {
-		return task, fmt.Errorf(ErrNotFound)
+		return t, fmt.Errorf(ErrNotFound)
 	}
-	return task, nil
+	return t, nil
 }
 
 // Обновляет задачу, переданную в запросе.
+// При смене даты или правила повторения серия начинается заново от новой даты.
 func (repo *Repository) UpdateTask(task task.Task) error {
-	_, err := task.GetNextRepeatDate()
+	err := task.Normalize()
 	if err != nil {
 		return err
 	}
+	if _, err = task.Rule(); err != nil {
+		return err
+	}
 
-	if _, err = time.Parse(DateFormat, task.Date); err != nil {
+	if task.Date.IsZero() {
 		return errors.New("wrong date")
 	}
 
@@ -191,110 +160,173 @@ func (repo *Repository) UpdateTask(task task.Task) error {
 		fmt.Println(errors.New("no id"))
 		return errors.New("no id")
 	}
-	row, err := repo.Repo.Exec("UPDATE scheduler SET date = :date, title = :title, comment = :comment, repeat = :repeat WHERE id = :id",
-		sql.Named("date", task.Date),
-		sql.Named("title", task.Title),
-		sql.Named("comment", task.Comment),
-		sql.Named("repeat", task.Repeat),
-		sql.Named("id", task.ID))
+
+	old, err := repo.GetTask(task.ID)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
-	ra, err := row.RowsAffected()
+	task.Start, task.Done, task.Exclude = old.Start, old.Done, old.Exclude
+	if task.Repeat != old.Repeat {
+		task.Start, task.Done, task.Exclude = task.Date, 0, nil
+	} else if !task.Date.Equal(old.Date) || task.Start.IsZero() {
+		task.Start = task.Date
+	}
+
+	return repo.saveTask(task)
+}
+
+// Записывает задачу и состояние ее серии повторений без проверок.
+// Переданные даты overdue сохраняются как отдельные просроченные задачи.
+func (repo *Repository) saveTask(task task.Task, overdue ...string) error {
+	extra, err := overdueTasks(task, overdue)
 	if err != nil {
 		return err
 	}
-	if ra != 1 {
-		return errors.New("no rows affected")
-	}
-	return nil
+	return repo.Store.Update(task, extra...)
 }
 
-// Механизм выполнения задачи: если поле repeat пустое - удаляет задачу,
-// в противном случае обновляет дату имеющейся задачи с тем же id.
+// Механизм выполнения задачи: если поле repeat пустое или серия повторений закончилась - удаляет задачу,
+// в противном случае переносит задачу на следующую дату и увеличивает счетчик выполнений.
 func (repo *Repository) DoneTask(id string) error {
-	task, err := repo.GetTask(id)
+	t, err := repo.GetTask(id)
 	if err != nil {
 		return err
 	}
 
-	if task.Repeat == "" {
+	if t.Repeat == "" {
 		repo.DeleteTask(id)
 		return nil
 	}
 
-	nextDate, err := task.GetNextRepeatDate()
-	if err != nil {
-		return err
+	t.Done++
+	if t.Finished() {
+		return repo.DeleteTask(id)
 	}
 
-	task.Date = nextDate
-	err = repo.UpdateTask(task)
-
+	next, overdue, err := t.Advance(repo.Clock.Now())
+	if errors.Is(err, task.ErrSeriesEnded) {
+		return repo.DeleteTask(id)
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	t.MoveTo(next)
+	// В режиме отсчета от выполнения серия начинается заново от новой даты
+	if t.Relative == task.RelativeCompletion {
+		t.Start = t.Date
+	}
+	return repo.saveTask(t, overdue...)
 }
 
 // Удаляет задачу с заданным ID.
 func (repo *Repository) DeleteTask(id string) error {
-	row, err := repo.Repo.Exec("DELETE FROM scheduler WHERE id=:id", sql.Named("id", id))
+	return repo.Store.Delete(id)
+}
+
+// Исключает дату из серии повторений задачи. Если исключается текущая дата задачи,
+// задача переносится на следующее повторение, а при его отсутствии удаляется.
+func (repo *Repository) ExcludeDate(id string, d task.Date) error {
+	t, err := repo.GetTask(id)
 	if err != nil {
 		return err
 	}
-	ra, err := row.RowsAffected()
-	if err != nil {
-		return err
+	if t.Repeat == "" {
+		return errors.New("задача не повторяется")
 	}
-	if ra != 1 {
-		return errors.New("no rows affected")
+
+	for _, v := range t.Exclude {
+		if v.Equal(d) {
+			return nil
+		}
 	}
-	return nil
-}
+	t.Exclude = append(t.Exclude, d)
 
-func (repo *Repository) SearchTask(search string) ([]task.Task, error) {
-	res := []task.Task{}
-	if date, err := time.Parse("02.01.2006", search); err == nil {
-		rows, err := repo.Repo.Query("SELECT * FROM scheduler WHERE date = :date", sql.Named("date", date.Format(DateFormat)))
+	if d.Equal(t.Date) {
+		next, err := t.NextAt(repo.Clock.Now())
+		if errors.Is(err, task.ErrSeriesEnded) {
+			return repo.DeleteTask(id)
+		}
 		if err != nil {
-			return res, err
+			return err
 		}
+		t.MoveTo(next)
+	}
+	return repo.saveTask(t)
+}
 
-		for rows.Next() {
-			task := task.Task{}
-			err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-			if err != nil {
-				return res, err
-			}
-			res = append(res, task)
-		}
+// Возвращает ранее исключенную дату в серию повторений задачи.
+func (repo *Repository) IncludeDate(id string, d task.Date) error {
+	t, err := repo.GetTask(id)
+	if err != nil {
+		return err
+	}
 
-		if err := rows.Err(); err != nil {
-			return res, err
+	exclude := []task.Date{}
+	for _, v := range t.Exclude {
+		if !v.Equal(d) {
+			exclude = append(exclude, v)
 		}
-
-		return res, nil
 	}
-	rows, err := repo.Repo.Query("SELECT * FROM scheduler WHERE title LIKE :search OR comment LIKE :search", sql.Named("search", "%"+search+"%"))
+	if len(exclude) == len(t.Exclude) {
+		return errors.New("дата не исключена")
+	}
+	t.Exclude = exclude
+	return repo.saveTask(t)
+}
+
+// Пропускает текущее повторение задачи: в отличие от DoneTask не увеличивает счетчик выполнений.
+func (repo *Repository) SkipTask(id string) error {
+	t, err := repo.GetTask(id)
 	if err != nil {
-		return res, err
+		return err
 	}
+	return repo.ExcludeDate(id, t.Date)
+}
 
-	for rows.Next() {
-		task := task.Task{}
-		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
-			return res, err
+// Ищет задачи по дате или диапазону дат (см. parseDateRange) либо по запросу на языке Query.
+// Запрос только из слов и фраз ищется по полнотекстовому индексу, за точными совпадениями
+// следуют совпадения с опечатками (см. fuzzySearch); остальные запросы ищутся по условиям
+// запроса. Найденные задачи упорядочены по релевантности, сниппеты отмечают найденные слова.
+// Относительные даты отсчитываются от часов репозитория. Ошибка разбора запроса имеет тип *QueryError.
+func (repo *Repository) SearchTask(search string) ([]task.Task, error) {
+	now := repo.Clock.Now()
+	from, to, err := parseDateRange(search, now)
+	if err == nil {
+		if from.Equal(to) {
+			return repo.Store.ListByDate(from)
 		}
-		res = append(res, task)
+		return repo.Store.Select(&Query{text: search, root: dateNode{op: "on", from: from, to: to}})
 	}
-
-	if err := rows.Err(); err != nil {
-		return res, err
+	if !errors.Is(err, errSearchDate) {
+		return nil, &QueryError{1, err.Error()}
 	}
 
-	return res, nil
+	q, err := ParseQuery(search, now)
+	if err != nil {
+		return nil, err
+	}
+	if q.plain() {
+		terms := q.terms()
+		tasks, err := repo.Store.Search(searchText(terms))
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]bool, len(tasks))
+		for _, t := range tasks {
+			found[t.ID] = true
+		}
+		// После точных совпадений - совпадения с опечатками
+		fuzzy, err := repo.fuzzySearch(terms, found)
+		if err != nil {
+			return nil, err
+		}
+		return append(tasks, fuzzy...), nil
+	}
+	tasks, err := repo.Store.Select(q)
+	if err != nil {
+		return nil, err
+	}
+	rankMatches(tasks, q.terms())
+	return tasks, nil
 }
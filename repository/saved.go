@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	task "todo/task"
+)
+
+// Сохраненный поиск (умный список): запрос SearchTask под именем. Результаты не хранятся,
+// а ищутся заново при каждом обращении, поэтому список всегда отражает текущие задачи и дату.
+type SavedSearch struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+var (
+	ErrSearchNotFound = errors.New("сохраненный поиск не найден")
+	ErrSearchExists   = errors.New("сохраненный поиск с таким именем уже есть")
+)
+
+// Проверяет имя и запрос сохраненного поиска и возвращает его с именем без пробелов по краям.
+// Запрос проверяется так же, как в SearchTask; ошибка разбора запроса имеет тип *QueryError.
+func checkSavedSearch(s SavedSearch, now time.Time) (SavedSearch, error) {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return s, errors.New("не указано имя сохраненного поиска")
+	}
+	if strings.Contains(s.Name, "/") {
+		return s, errors.New("имя сохраненного поиска не может содержать /")
+	}
+	if strings.TrimSpace(s.Query) == "" {
+		return s, errors.New("не указан запрос сохраненного поиска")
+	}
+	_, _, err := parseDateRange(s.Query, now)
+	if err == nil {
+		return s, nil
+	}
+	if !errors.Is(err, errSearchDate) {
+		return s, &QueryError{1, err.Error()}
+	}
+	_, err = ParseQuery(s.Query, now)
+	return s, err
+}
+
+// Возвращает сохраненные поиски, упорядоченные по имени.
+func (repo *Repository) SavedSearches() ([]SavedSearch, error) {
+	return repo.Store.Searches()
+}
+
+// Возвращает сохраненный поиск по имени. Если поиска нет, возвращает ErrSearchNotFound.
+func (repo *Repository) GetSavedSearch(name string) (SavedSearch, error) {
+	s, err := repo.Store.GetSearch(name)
+	if errors.Is(err, errNoRows) {
+		return s, ErrSearchNotFound
+	}
+	return s, err
+}
+
+// Сохраняет новый поиск. Если поиск с таким именем уже есть, возвращает ErrSearchExists.
+func (repo *Repository) AddSavedSearch(s SavedSearch) (SavedSearch, error) {
+	s, err := checkSavedSearch(s, repo.Clock.Now())
+	if err != nil {
+		return s, err
+	}
+	return s, repo.Store.InsertSearch(s)
+}
+
+// Заменяет запрос сохраненного поиска s.Name. Если поиска нет, возвращает ErrSearchNotFound.
+func (repo *Repository) UpdateSavedSearch(s SavedSearch) (SavedSearch, error) {
+	s, err := checkSavedSearch(s, repo.Clock.Now())
+	if err != nil {
+		return s, err
+	}
+	if err := repo.Store.UpdateSearch(s); err != nil {
+		if errors.Is(err, errNoRows) {
+			return s, ErrSearchNotFound
+		}
+		return s, err
+	}
+	return s, nil
+}
+
+// Удаляет сохраненный поиск. Если поиска нет, возвращает ErrSearchNotFound.
+func (repo *Repository) DeleteSavedSearch(name string) error {
+	err := repo.Store.DeleteSearch(name)
+	if errors.Is(err, errNoRows) {
+		return ErrSearchNotFound
+	}
+	return err
+}
+
+// Возвращает текущие результаты сохраненного поиска: запрос выполняется SearchTask
+// по часам репозитория, так что относительные даты ("today", "overdue") сдвигаются вместе с ними.
+func (repo *Repository) SavedSearchTasks(name string) ([]task.Task, error) {
+	s, err := repo.GetSavedSearch(name)
+	if err != nil {
+		return nil, err
+	}
+	return repo.SearchTask(s.Query)
+}
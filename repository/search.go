@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"html"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	task "todo/task"
+)
+
+// Метки начала и конца найденного слова в сниппете до преобразования в HTML.
+const (
+	markOpen  = "\x02"
+	markClose = "\x03"
+)
+
+// Наибольшая длина сниппета в символах без учета многоточий.
+const snippetLen = 80
+
+// Слово или фраза поискового запроса.
+type searchTerm struct {
+	text   string
+	phrase bool // фраза в кавычках ищется целиком
+}
+
+// Разбирает поисковый запрос на слова и фразы в двойных кавычках. Слово ищется как префикс
+// слов задачи ("балан" и "балан*" находят "Баланс"), фраза - как последовательность слов.
+// Слова без букв и цифр отбрасываются.
+func parseSearch(text string) []searchTerm {
+	var terms []searchTerm
+	add := func(s string, phrase bool) {
+		s = strings.TrimSpace(strings.TrimRight(s, "*"))
+		if hasWordChars(s) {
+			terms = append(terms, searchTerm{text: s, phrase: phrase})
+		}
+	}
+	for text != "" {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if strings.HasPrefix(text, `"`) {
+			end := strings.Index(text[1:], `"`)
+			if end < 0 {
+				add(text[1:], true)
+				break
+			}
+			add(text[1:end+1], true)
+			text = text[end+2:]
+			continue
+		}
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end < 0 {
+			end = len(text)
+		}
+		add(text[:end], false)
+		text = text[end:]
+	}
+	return terms
+}
+
+// Возвращает запрос из слов и фраз terms в виде, который разбирает parseSearch.
+func searchText(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.text
+		if t.phrase {
+			parts[i] = `"` + t.text + `"`
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Сообщает, есть ли в s буквы или цифры.
+func hasWordChars(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0
+}
+
+// Возвращает запрос FTS5 MATCH: все слова и фразы должны встретиться в задаче.
+// Каждое слово берется в кавычки, поэтому операторы FTS5 в тексте запроса не действуют.
+func ftsQuery(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.fts()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Возвращает слово или фразу в синтаксисе FTS5.
+func (t searchTerm) fts() string {
+	s := `"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`
+	if !t.phrase {
+		s += "*"
+	}
+	return s
+}
+
+// Возвращает байтовые позиции вхождений sub в s без учета регистра (для всех алфавитов, см. foldRune).
+func foldIndexes(s, sub string) [][2]int {
+	q := foldRunes(sub)
+	if len(q) == 0 {
+		return nil
+	}
+	var res [][2]int
+	for i := 0; i < len(s); {
+		j, k := i, 0
+		for ; k < len(q) && j < len(s); k++ {
+			r, size := utf8.DecodeRuneInString(s[j:])
+			if foldRune(r) != q[k] {
+				break
+			}
+			j += size
+		}
+		if k == len(q) {
+			res = append(res, [2]int{i, j})
+			i = j
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return res
+}
+
+// Сообщает, встречается ли sub в s без учета регистра.
+func foldContains(s, sub string) bool {
+	return len(foldIndexes(s, sub)) > 0
+}
+
+// Возвращает байтовые границы вхождений слова или фразы term в text так, как их находит
+// индекс FTS5: слова term идут в тексте подряд и совпадают после foldRune, а последнее
+// слово (кроме фраз) может быть началом слова текста.
+func termIndexes(text string, term searchTerm) [][2]int {
+	q := splitWords(term.text)
+	if len(q) == 0 {
+		return nil
+	}
+	words := splitWords(text)
+	var res [][2]int
+	for i := 0; i+len(q) <= len(words); i++ {
+		ok := true
+		for k, w := range q {
+			tw := words[i+k].folded
+			if k == len(q)-1 && !term.phrase && len(tw) > len(w.folded) {
+				tw = tw[:len(w.folded)]
+			}
+			if !slices.Equal(tw, w.folded) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			res = append(res, [2]int{words[i].start, words[i+len(q)-1].end})
+			i += len(q) - 1
+		}
+	}
+	return res
+}
+
+// Сообщает, встречается ли слово или фраза term в text (см. termIndexes).
+func containsTerm(text string, term searchTerm) bool {
+	return len(termIndexes(text, term)) > 0
+}
+
+// Сообщает, встречаются ли все слова запроса в заголовке или комментарии задачи.
+func matchTerms(t task.Task, terms []searchTerm) bool {
+	for _, term := range terms {
+		if !containsTerm(t.Title, term) && !containsTerm(t.Comment, term) {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// Возвращает регулярное выражение POSIX, находящее слово или фразу term в тексте,
+// приведенном sqlFold, так же, как termIndexes.
+func termPattern(term searchTerm) string {
+	var words []string
+	for _, w := range splitWords(term.text) {
+		words = append(words, sqlFold(term.text[w.start:w.end]))
+	}
+	p := "(^|[^[:alnum:]])" + strings.Join(words, "[^[:alnum:]]+")
+	if term.phrase {
+		p += "([^[:alnum:]]|$)"
+	}
+	return p
+}
+
+// Возвращает текст text, в котором вхождения слов запроса отмечены метками markOpen и markClose,
+// и число отмеченных вхождений. Длинный текст обрезается вокруг первого вхождения.
+func markTerms(text string, terms []searchTerm) (string, int) {
+	marked := make([]bool, len(text)+1)
+	starts := make(map[int]bool)
+	hits := 0
+	for _, term := range terms {
+		for _, m := range termIndexes(text, term) {
+			starts[m[0]] = true
+			for i := m[0]; i < m[1]; i++ {
+				marked[i] = true
+			}
+			hits++
+		}
+	}
+	if hits == 0 {
+		return text, 0
+	}
+
+	// Окно вокруг первого вхождения
+	first := len(text)
+	for i := range starts {
+		first = min(first, i)
+	}
+	from, to, prefix, suffix := 0, len(text), "", ""
+	if utf8.RuneCountInString(text) > snippetLen {
+		runes := []rune(text)
+		at := utf8.RuneCountInString(text[:first])
+		lo := max(0, at-snippetLen/4)
+		hi := min(len(runes), lo+snippetLen)
+		lo = max(0, hi-snippetLen)
+		from, to = len(string(runes[:lo])), len(string(runes[:hi]))
+		if lo > 0 {
+			prefix = "…"
+		}
+		if hi < len(runes) {
+			suffix = "…"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for i := from; i < to; {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		if marked[i] && (i == from || !marked[i-1]) {
+			b.WriteString(markOpen)
+		}
+		b.WriteString(text[i : i+size])
+		if marked[i] && (i+size == to || !marked[i+size]) {
+			b.WriteString(markClose)
+		}
+		i += size
+	}
+	b.WriteString(suffix)
+	return b.String(), hits
+}
+
+// Преобразует текст с метками в HTML: экранирует текст и заменяет метки на <mark>.
+func highlight(s string) string {
+	s = html.EscapeString(s)
+	return strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>").Replace(s)
+}
+
+// Заполняет сниппеты найденных задач и упорядочивает их по релевантности: сначала задачи
+// с совпадениями в заголовке, затем с большим числом совпадений. Порядок задач с равной
+// релевантностью не меняется. Используется хранилищами без полнотекстового индекса.
+func rankMatches(tasks []task.Task, terms []searchTerm) {
+	if len(terms) == 0 {
+		return
+	}
+	type rank struct{ title, hits int }
+	ranks := make(map[string]rank, len(tasks))
+	for i, t := range tasks {
+		title, titleHits := markTerms(t.Title, terms)
+		comment, commentHits := markTerms(t.Comment, terms)
+		snippet := title
+		if titleHits == 0 && commentHits > 0 {
+			snippet = comment
+		}
+		tasks[i].Snippet = highlight(snippet)
+		ranks[t.ID] = rank{titleHits, titleHits + commentHits}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := ranks[tasks[i].ID], ranks[tasks[j].ID]
+		if (a.title > 0) != (b.title > 0) {
+			return a.title > 0
+		}
+		return a.hits > b.hits
+	})
+}
@@ -0,0 +1,571 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	task "todo/task"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Различия СУБД, которые учитывает sqlStore.
+type dialect struct {
+	driver      string
+	groupConcat string // агрегат, собирающий e.date через запятую
+	fullText    bool   // поиск по полнотекстовым индексам FTS5 scheduler_fts и scheduler_trigram
+	fold        string // без fullText: выражение, приводящее текст %s к виду sqlFold
+	tableExists string // запрос, возвращающий число таблиц с именем из параметра
+	migrations  []Migration
+}
+
+// Заменяет параметры ? на принятые в СУБД ($1, $2 в PostgreSQL).
+func (d dialect) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(d.driver), query)
+}
+
+// Хранилище задач в SQL-базе. Одна реализация обслуживает SQLite и PostgreSQL,
+// различия СУБД описаны в dialect.
+type sqlStore struct {
+	db *sql.DB
+	d  dialect
+}
+
+// Открывает БД драйвером диалекта d и применяет к ней миграции схемы.
+func openSQLStore(d dialect, dsn string) (*sqlStore, error) {
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err = d.migrate(db, false); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlStore{db: db, d: d}, nil
+}
+
+// Запрос, выбирающий задачи вместе с состоянием серии повторений.
+func (s *sqlStore) selectTasks() string {
+	return "SELECT " + s.taskColumns() + " FROM scheduler s" + taskJoins
+}
+
+// Столбцы задачи в порядке, который ожидает scanTask.
+func (s *sqlStore) taskColumns() string {
+	return fmt.Sprintf(`s.id, s.date, COALESCE(tt.time, ''), s.title, s.comment, s.repeat,
+	COALESCE(r.start, ''), COALESCE(r.until, ''), COALESCE(r.count, 0), COALESCE(r.done, 0),
+	COALESCE(r.missed, ''), COALESCE(r.relative, ''),
+	COALESCE((SELECT %s FROM repeat_exdate e WHERE e.task_id = s.id), '')`, s.d.groupConcat)
+}
+
+// Соединения с таблицами состояния серии и времени задачи.
+const taskJoins = " LEFT JOIN repeat_state r ON r.task_id = s.id LEFT JOIN task_time tt ON tt.task_id = s.id"
+
+// Порядок задач в списках: по дате, затем по времени.
+const orderTasks = " ORDER BY s.date, COALESCE(tt.time, ''), s.id"
+
+// Общий интерфейс для *sql.DB и *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Считывает задачу из строки результата selectTasks. Значения столбцов после
+// столбцов задачи считываются в extra.
+func scanTask(row scanner, extra ...any) (task.Task, error) {
+	t := task.Task{}
+	var exclude string
+	dest := []any{&t.ID, &t.Date, &t.Time, &t.Title, &t.Comment, &t.Repeat, &t.Start, &t.Until, &t.Count, &t.Done, &t.Missed, &t.Relative, &exclude}
+	if err := row.Scan(append(dest, extra...)...); err != nil || exclude == "" {
+		return t, err
+	}
+	for _, v := range strings.Split(exclude, ",") {
+		d, err := task.ParseDate(v)
+		if err != nil {
+			return t, err
+		}
+		t.Exclude = append(t.Exclude, d)
+	}
+	task.SortDates(t.Exclude)
+	return t, nil
+}
+
+// Выполняет запрос с параметрами ? и возвращает найденные задачи.
+func (s *sqlStore) query(query string, args ...any) ([]task.Task, error) {
+	result := []task.Task{}
+
+	rows, err := s.db.Query(s.d.rebind(query), args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) Get(id string) (task.Task, error) {
+	row := s.db.QueryRow(s.d.rebind(s.selectTasks()+" WHERE s.id = ?"), id)
+	return scanTask(row)
+}
+
+func (s *sqlStore) List(limit int) ([]task.Task, error) {
+	return s.query(s.selectTasks()+orderTasks+" LIMIT ?", limit)
+}
+
+func (s *sqlStore) ListByDate(date task.Date) ([]task.Task, error) {
+	return s.query(s.selectTasks()+" WHERE s.date = ?"+orderTasks, date)
+}
+
+func (s *sqlStore) Search(text string) ([]task.Task, error) {
+	terms := parseSearch(text)
+	if len(terms) == 0 {
+		return []task.Task{}, nil
+	}
+	if s.d.fullText {
+		return s.searchFullText(terms)
+	}
+
+	var (
+		conds []string
+		args  []any
+	)
+	for _, term := range terms {
+		title, arg := s.termCond("s.title", term)
+		comment, _ := s.termCond("s.comment", term)
+		conds = append(conds, "("+title+" OR "+comment+")")
+		args = append(args, arg, arg)
+	}
+	tasks, err := s.query(s.selectTasks()+" WHERE "+strings.Join(conds, " AND ")+orderTasks, args...)
+	if err != nil {
+		return tasks, err
+	}
+	rankMatches(tasks, terms)
+	return tasks, nil
+}
+
+// Возвращает условие "слово или фраза term встречается в столбце column" для СУБД без
+// полнотекстового индекса и его параметр: регулярное выражение termPattern по тексту,
+// приведенному dialect.fold.
+func (s *sqlStore) termCond(column string, term searchTerm) (string, any) {
+	return fmt.Sprintf(s.d.fold, "COALESCE("+column+", '')") + " ~ ?", termPattern(term)
+}
+
+func (s *sqlStore) Candidates(grams [][]string) ([]task.Task, error) {
+	if len(grams) == 0 {
+		return []task.Task{}, nil
+	}
+	var (
+		conds, match []string
+		args         []any
+	)
+	for _, word := range grams {
+		// Индекс троек букв не ищет строки короче трех букв, их ищет LIKE
+		if s.d.fullText && utf8.RuneCountInString(word[0]) >= 3 {
+			// Тройки состоят только из букв и цифр, поэтому кавычки в них не встречаются
+			match = append(match, `("`+strings.Join(word, `" OR "`)+`")`)
+			continue
+		}
+		var alts []string
+		seen := make(map[string]bool)
+		for _, g := range word {
+			// LIKE в SQLite не учитывает регистр только латиницы, но word содержит все написания
+			// слова (см. spellings); в остальных СУБД текст и g приводятся к виду sqlFold
+			cond, like := "%s LIKE ?", "%"+g+"%"
+			if !s.d.fullText {
+				cond, like = s.d.fold+" LIKE ?", "%"+sqlFold(g)+"%"
+			}
+			if seen[like] {
+				continue
+			}
+			seen[like] = true
+			alts = append(alts, fmt.Sprintf(cond, "s.title")+" OR "+fmt.Sprintf(cond, "s.comment"))
+			args = append(args, like, like)
+		}
+		conds = append(conds, "("+strings.Join(alts, " OR ")+")")
+	}
+	if len(match) > 0 {
+		conds = append(conds, "s.id IN (SELECT rowid FROM scheduler_trigram WHERE scheduler_trigram MATCH ?)")
+		args = append(args, strings.Join(match, " AND "))
+	}
+	return s.query(s.selectTasks()+" WHERE "+strings.Join(conds, " AND ")+orderTasks, args...)
+}
+
+func (s *sqlStore) Select(q *Query) ([]task.Task, error) {
+	if q.root == nil {
+		return []task.Task{}, nil
+	}
+	where, args := s.compile(q.root)
+	return s.query(s.selectTasks()+" WHERE "+where+orderTasks, args...)
+}
+
+// Переводит узел поискового запроса в условие WHERE с параметрами ?.
+func (s *sqlStore) compile(n queryNode) (string, []any) {
+	switch n := n.(type) {
+	case andNode:
+		return s.compileBinary("AND", n.left, n.right)
+	case orNode:
+		return s.compileBinary("OR", n.left, n.right)
+	case notNode:
+		c, args := s.compile(n.node)
+		return "NOT " + c, args
+	case textNode:
+		if s.d.fullText {
+			match := n.term.fts()
+			if n.field != "" {
+				match = n.field + " : " + match
+			}
+			return "s.id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?)", []any{match}
+		}
+		if n.field != "" {
+			cond, arg := s.termCond("s."+n.field, n.term)
+			return cond, []any{arg}
+		}
+		title, arg := s.termCond("s.title", n.term)
+		comment, _ := s.termCond("s.comment", n.term)
+		return "(" + title + " OR " + comment + ")", []any{arg, arg}
+	case repeatNode:
+		switch n.kind {
+		case "any":
+			return "s.repeat <> ''", nil
+		case "none":
+			return "s.repeat = ''", nil
+		}
+		// Правило записано коротким синтаксисом ("d 5", "y") или RRULE
+		return "(s.repeat = ? OR s.repeat LIKE ? OR s.repeat LIKE ?)",
+			[]any{n.kind, n.kind + " %", "%FREQ=" + repeatRRules[n.kind] + "%"}
+	case dateNode:
+		switch {
+		case n.op == "before":
+			return "s.date < ?", []any{n.from}
+		case n.op == "after":
+			return "s.date > ?", []any{n.to}
+		case n.from.IsZero():
+			return "s.date <= ?", []any{n.to}
+		}
+		return "(s.date >= ? AND s.date <= ?)", []any{n.from, n.to}
+	}
+	panic(fmt.Sprintf("неизвестный узел запроса %T", n))
+}
+
+// Соединяет условия left и right оператором op.
+func (s *sqlStore) compileBinary(op string, left, right queryNode) (string, []any) {
+	l, largs := s.compile(left)
+	r, rargs := s.compile(right)
+	return "(" + l + " " + op + " " + r + ")", append(largs, rargs...)
+}
+
+// Ищет задачи по полнотекстовому индексу scheduler_fts и упорядочивает их по релевантности (bm25).
+func (s *sqlStore) searchFullText(terms []searchTerm) ([]task.Task, error) {
+	query := "SELECT " + s.taskColumns() + `, m.snippet FROM scheduler s
+	JOIN (SELECT rowid, bm25(scheduler_fts) AS rank,
+		snippet(scheduler_fts, -1, char(2), char(3), '…', 12) AS snippet
+		FROM scheduler_fts WHERE scheduler_fts MATCH ?) m ON m.rowid = s.id` + taskJoins +
+		" ORDER BY m.rank, s.date, COALESCE(tt.time, ''), s.id"
+
+	result := []task.Task{}
+	rows, err := s.db.Query(s.d.rebind(query), ftsQuery(terms))
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snippet string
+		t, err := scanTask(rows, &snippet)
+		if err != nil {
+			return result, err
+		}
+		t.Snippet = highlight(snippet)
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// Возвращает условие WHERE для фильтров f без учета позиции f.After и его параметры.
+func filterWhere(f Filter) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	if !f.From.IsZero() {
+		conds = append(conds, "s.date >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "s.date <= ?")
+		args = append(args, f.To)
+	}
+	switch f.Repeat {
+	case RepeatOnly:
+		conds = append(conds, "s.repeat <> ''")
+	case RepeatNone:
+		conds = append(conds, "s.repeat = ''")
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// Столбцы ключа сортировки sortKey.
+func sortColumns(sort string) []string {
+	if sort == SortTitle {
+		return []string{"s.title", "s.id"}
+	}
+	return []string{"s.date", "COALESCE(tt.time, '')", "s.id"}
+}
+
+func (s *sqlStore) Find(f Filter) ([]task.Task, int, error) {
+	where, args := filterWhere(f)
+
+	var total int
+	err := s.db.QueryRow(s.d.rebind("SELECT COUNT(*) FROM scheduler s"+where), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cols := sortColumns(f.Sort)
+	dir, cmp := "", ">"
+	if f.Desc {
+		dir, cmp = " DESC", "<"
+	}
+	if f.After != nil {
+		// Сравнение строк значений (ключ, id) продолжает список с позиции курсора
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+		cond := fmt.Sprintf("(%s) %s (%s)", strings.Join(cols, ", "), cmp, marks)
+		if where == "" {
+			where = " WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+		for _, k := range f.After.Key {
+			args = append(args, k)
+		}
+		args = append(args, f.After.ID)
+	}
+	order := " ORDER BY " + strings.Join(cols, dir+", ") + dir
+
+	tasks, err := s.query(s.selectTasks()+where+order+" LIMIT ?", append(args, f.Limit)...)
+	return tasks, total, err
+}
+
+func (s *sqlStore) Insert(t task.Task, extra ...task.Task) (string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	id, err := s.insert(tx, t)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range extra {
+		if _, err := s.insert(tx, e); err != nil {
+			return "", err
+		}
+	}
+	return id, tx.Commit()
+}
+
+func (s *sqlStore) Update(t task.Task, extra ...task.Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row, err := tx.Exec(s.d.rebind("UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?"),
+		t.Date, t.Title, t.Comment, t.Repeat, t.ID)
+	if err != nil {
+		return err
+	}
+	ra, err := row.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if ra != 1 {
+		return errNoRows
+	}
+
+	if err := s.saveState(tx, t); err != nil {
+		return err
+	}
+	for _, e := range extra {
+		if _, err := s.insert(tx, e); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Delete(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row, err := tx.Exec(s.d.rebind("DELETE FROM scheduler WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	ra, err := row.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if ra != 1 {
+		return errNoRows
+	}
+
+	for _, table := range []string{"repeat_state", "repeat_exdate", "task_time"} {
+		if _, err := tx.Exec(s.d.rebind("DELETE FROM "+table+" WHERE task_id = ?"), id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Searches() ([]SavedSearch, error) {
+	rows, err := s.db.Query("SELECT name, query FROM saved_search ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []SavedSearch{}
+	for rows.Next() {
+		var v SavedSearch
+		if err := rows.Scan(&v.Name, &v.Query); err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, rows.Err()
+}
+
+func (s *sqlStore) GetSearch(name string) (SavedSearch, error) {
+	v := SavedSearch{Name: name}
+	err := s.db.QueryRow(s.d.rebind("SELECT query FROM saved_search WHERE name = ?"), name).Scan(&v.Query)
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, errNoRows
+	}
+	return v, err
+}
+
+func (s *sqlStore) InsertSearch(v SavedSearch) error {
+	row, err := s.db.Exec(s.d.rebind("INSERT INTO saved_search (name, query) VALUES (?, ?) ON CONFLICT DO NOTHING"),
+		v.Name, v.Query)
+	if err != nil {
+		return err
+	}
+	ra, err := row.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if ra != 1 {
+		return ErrSearchExists
+	}
+	return nil
+}
+
+func (s *sqlStore) UpdateSearch(v SavedSearch) error {
+	return s.execSearch("UPDATE saved_search SET query = ? WHERE name = ?", v.Query, v.Name)
+}
+
+func (s *sqlStore) DeleteSearch(name string) error {
+	return s.execSearch("DELETE FROM saved_search WHERE name = ?", name)
+}
+
+// Выполняет запрос, изменяющий один сохраненный поиск. Если поиска нет, возвращает errNoRows.
+func (s *sqlStore) execSearch(query string, args ...any) error {
+	row, err := s.db.Exec(s.d.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	ra, err := row.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if ra != 1 {
+		return errNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// Добавляет строку в scheduler вместе с состоянием задачи и возвращает id новой задачи.
+func (s *sqlStore) insert(db execer, t task.Task) (string, error) {
+	var id int64
+	err := db.QueryRow(s.d.rebind("INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?) RETURNING id"),
+		t.Date, t.Title, t.Comment, t.Repeat).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	t.ID = strconv.FormatInt(id, 10)
+	return t.ID, s.saveState(db, t)
+}
+
+// Сохраняет состояние серии повторений задачи вместе с исключенными датами и время задачи.
+// Для неповторяющейся задачи состояние серии удаляется, для задачи на весь день - время.
+func (s *sqlStore) saveState(db execer, t task.Task) error {
+	if _, err := db.Exec(s.d.rebind("DELETE FROM repeat_exdate WHERE task_id = ?"), t.ID); err != nil {
+		return err
+	}
+	if t.Repeat == "" {
+		if _, err := db.Exec(s.d.rebind("DELETE FROM repeat_state WHERE task_id = ?"), t.ID); err != nil {
+			return err
+		}
+	} else {
+		for _, date := range t.Exclude {
+			_, err := db.Exec(s.d.rebind("INSERT INTO repeat_exdate (task_id, date) VALUES (?, ?) ON CONFLICT DO NOTHING"),
+				t.ID, date)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := db.Exec(s.d.rebind(`INSERT INTO repeat_state (task_id, start, until, count, done, missed, relative)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(task_id) DO UPDATE SET start = excluded.start, until = excluded.until, count = excluded.count,
+			done = excluded.done, missed = excluded.missed, relative = excluded.relative`),
+			t.ID, t.Start, t.Until, t.Count, t.Done, t.Missed, t.Relative)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.Time == "" {
+		_, err := db.Exec(s.d.rebind("DELETE FROM task_time WHERE task_id = ?"), t.ID)
+		return err
+	}
+	_, err := db.Exec(s.d.rebind(`INSERT INTO task_time (task_id, time) VALUES (?, ?)
+		ON CONFLICT(task_id) DO UPDATE SET time = excluded.time`), t.ID, t.Time)
+	return err
+}
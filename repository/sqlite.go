@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+var sqliteDialect = dialect{
+	driver:      "sqlite",
+	groupConcat: "group_concat(e.date, ',')",
+	fullText:    true,
+	tableExists: "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+	migrations:  sqliteMigrations,
+}
+
+func init() {
+	// Пустая dsn - файл TODO_DFILE или scheduler.db рядом с программой
+	registerSQL("sqlite", sqliteDialect, func(dsn string) string {
+		if dsn == "" {
+			return dbFile()
+		}
+		return dsn
+	})
+}
+
+// Возвращает путь к файлу БД SQLite: TODO_DFILE или scheduler.db рядом с программой.
+func dbFile() string {
+	dbFile := os.Getenv("TODO_DFILE")
+	if dbFile == "" {
+		dbFile = dbCheck()
+	}
+
+	return dbFile
+}
+
+// Вспомогательная функция, проверяющая наличие БД в месте запуска программы.
+func dbCheck() string {
+	appPath, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dbFile := filepath.Join(filepath.Dir(appPath), "scheduler.db")
+	_, err = os.Stat(dbFile)
+
+	if err != nil {
+		os.Create("scheduler.db")
+	}
+
+	return dbFile
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	task "todo/task"
+)
+
+// Хранилище задач. Repository реализует поверх него логику задач (повторения, перенос дат),
+// а хранилище только сохраняет и находит задачи вместе с состоянием серии повторений и временем.
+type Store interface {
+	// Возвращает задачу по id. Если задачи нет, возвращает ошибку.
+	Get(id string) (task.Task, error)
+	// Возвращает не больше limit задач, упорядоченных по дате и времени.
+	List(limit int) ([]task.Task, error)
+	// Возвращает задачи на дату date.
+	ListByDate(date task.Date) ([]task.Task, error)
+	// Возвращает задачи, в заголовке или комментарии которых встречаются все слова и фразы
+	// запроса text (см. parseSearch), по убыванию релевантности и с заполненным Snippet.
+	Search(text string) ([]task.Task, error)
+	// Возвращает кандидатов нечеткого поиска: задачи, в заголовке или комментарии которых
+	// для каждого элемента grams встречается хотя бы одна из его строк без учета регистра.
+	// Строки элемента - тройки букв или все написания слова короче трех букв.
+	Candidates(grams [][]string) ([]task.Task, error)
+	// Возвращает задачи, подходящие под поисковый запрос q, упорядоченные по дате и времени.
+	Select(q *Query) ([]task.Task, error)
+	// Возвращает не больше f.Limit задач, подходящих под фильтры f, начиная после позиции f.After,
+	// и общее число подходящих задач без учета f.After и f.Limit.
+	Find(f Filter) ([]task.Task, int, error)
+	// Добавляет задачу t и задачи extra в одной транзакции и возвращает id задачи t.
+	Insert(t task.Task, extra ...task.Task) (string, error)
+	// Сохраняет задачу t и добавляет задачи extra в одной транзакции.
+	// Если задачи t нет, возвращает ошибку.
+	Update(t task.Task, extra ...task.Task) error
+	// Удаляет задачу вместе с ее состоянием. Если задачи нет, возвращает ошибку.
+	Delete(id string) error
+	// Возвращает сохраненные поиски, упорядоченные по имени.
+	Searches() ([]SavedSearch, error)
+	// Возвращает сохраненный поиск по имени. Если поиска нет, возвращает errNoRows.
+	GetSearch(name string) (SavedSearch, error)
+	// Добавляет сохраненный поиск. Если поиск с таким именем уже есть, возвращает ErrSearchExists.
+	InsertSearch(s SavedSearch) error
+	// Заменяет запрос сохраненного поиска s.Name. Если поиска нет, возвращает errNoRows.
+	UpdateSearch(s SavedSearch) error
+	// Удаляет сохраненный поиск. Если поиска нет, возвращает errNoRows.
+	DeleteSearch(name string) error
+	Close() error
+}
+
+// Ошибка изменения задачи, которой нет в хранилище.
+var errNoRows = errors.New("no rows affected")
+
+// Функция, открывающая хранилище по строке подключения dsn.
+type Opener func(dsn string) (Store, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Opener)
+)
+
+// Хранилище по умолчанию.
+const DefaultBackend = "sqlite"
+
+// Регистрирует хранилище под именем name. Вызывается из init файлов хранилищ.
+func Register(name string, open Opener) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, ok := backends[name]; ok {
+		panic("repository: хранилище " + name + " уже зарегистрировано")
+	}
+	backends[name] = open
+}
+
+// Возвращает имена зарегистрированных хранилищ.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Открывает хранилище name (sqlite, memory, postgres) со строкой подключения dsn
+// и возвращает репозиторий поверх него. Пустое name - хранилище по умолчанию.
+func Open(name, dsn string) (*Repository, error) {
+	if name == "" {
+		name = DefaultBackend
+	}
+	backendsMu.RLock()
+	open, ok := backends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("неизвестное хранилище %q, доступны: %v", name, Backends())
+	}
+
+	store, err := open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{Store: store, Clock: task.SystemClock}, nil
+}
+
+// Открывает хранилище, заданное переменными окружения TODO_STORAGE и TODO_DSN.
+func OpenFromEnv() (*Repository, error) {
+	return Open(os.Getenv("TODO_STORAGE"), os.Getenv("TODO_DSN"))
+}
@@ -5,39 +5,70 @@ import (
 	"net/http"
 
 	"todo/handlers"
+	"todo/repository"
+	"todo/task"
 )
 
 type Server struct {
 	HttpServer *http.Server
 	Handler    handlers.HandleProcesser
+	WebDir     string // каталог файлов фронтенда
 }
 
 func NewSrv() Server {
 	h := handlers.NewHandler()
 	srv := new(http.Server)
-	return Server{HttpServer: srv, Handler: h}
+	return Server{HttpServer: srv, Handler: h, WebDir: "./web"}
 }
 
-// Инициализирует сервер со всем нужными ручками для работы с фронтом.
-func (s Server) Run(port string) {
+// Возвращает сервер с заданными репозиторием и часами, например для запуска в тестах
+// с репозиторием в памяти и фиксированным временем.
+func NewSrvWith(rp repository.RepositoryProcesser, clock task.Clock) Server {
+	h := handlers.Handler{RP: rp, Clock: clock}
+	return Server{HttpServer: new(http.Server), Handler: h, WebDir: "./web"}
+}
+
+// Возвращает маршрутизатор со всеми нужными ручками для работы с фронтом.
+func (s Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("/", http.FileServer(http.Dir(s.WebDir)))
+
+	mux.HandleFunc("/api/nextdate", s.Handler.HandleDate)
+
+	mux.HandleFunc("/api/nextdates", s.Handler.AuthMiddleware(s.Handler.HandleDates))
+
+	mux.HandleFunc("/api/repeat", s.Handler.AuthMiddleware(s.Handler.ParseRepeatHandle))
 
-	webDir := "./web"
+	mux.HandleFunc("/api/task", s.Handler.AuthMiddleware(s.Handler.HandleTask))
 
-	http.Handle("/", http.FileServer(http.Dir(webDir)))
+	mux.HandleFunc("/api/tasks", s.Handler.AuthMiddleware(s.Handler.GetTasksHandle))
 
-	http.HandleFunc("/api/nextdate", s.Handler.HandleDate)
+	mux.HandleFunc("/api/task/done", s.Handler.AuthMiddleware(s.Handler.DoneTaskeHandle))
 
-	http.HandleFunc("/api/task", s.Handler.AuthMiddleware(s.Handler.HandleTask))
+	mux.HandleFunc("/api/task/skip", s.Handler.AuthMiddleware(s.Handler.SkipTaskHandle))
 
-	http.HandleFunc("/api/tasks", s.Handler.AuthMiddleware(s.Handler.GetTasksHandle))
+	mux.HandleFunc("/api/task/exdate", s.Handler.AuthMiddleware(s.Handler.ExdateHandle))
 
-	http.HandleFunc("/api/task/done", s.Handler.AuthMiddleware(s.Handler.DoneTaskeHandle))
+	mux.HandleFunc("/api/lists", s.Handler.AuthMiddleware(s.Handler.HandleLists))
 
-	http.HandleFunc("/api/signin", s.Handler.Auth)
+	mux.HandleFunc("/api/lists/{name}", s.Handler.AuthMiddleware(s.Handler.HandleList))
+
+	mux.HandleFunc("/api/lists/{name}/tasks", s.Handler.AuthMiddleware(s.Handler.HandleListTasks))
+
+	mux.HandleFunc("/api/signin", s.Handler.Auth)
+
+	return mux
+}
+
+// Инициализирует сервер со всем нужными ручками для работы с фронтом.
+func (s Server) Run(port string) {
+	s.HttpServer.Addr = port
+	s.HttpServer.Handler = s.Routes()
 
 	fmt.Println("Server starting at", port)
 
-	err := http.ListenAndServe(port, nil)
+	err := s.HttpServer.ListenAndServe()
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,186 @@
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Производственный календарь: выходные дни недели, праздники и рабочие дни,
+// перенесенные на выходные. По нему правила с модификаторами bd, bd+ и bd- пропускают
+// или переносят нерабочие дни.
+type Calendar struct {
+	Weekend  map[time.Weekday]bool
+	Holidays map[time.Time]bool
+	Workdays map[time.Time]bool // рабочие субботы и воскресенья
+}
+
+// Сколько дней подряд не больше ищется рабочий день при переносе.
+const maxHolidayRun = 366
+
+var defaultCalendar = NewCalendar()
+
+// Возвращает календарь с выходными в субботу и воскресенье и без праздников.
+func NewCalendar() *Calendar {
+	return &Calendar{
+		Weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		Holidays: make(map[time.Time]bool),
+		Workdays: make(map[time.Time]bool),
+	}
+}
+
+// Устанавливает календарь, по которому вычисляются рабочие дни во всех правилах повторения.
+// Вызывается при запуске сервера; nil возвращает календарь по умолчанию.
+func SetCalendar(c *Calendar) {
+	if c == nil {
+		c = NewCalendar()
+	}
+	defaultCalendar = c
+}
+
+// Сообщает, рабочий ли день d.
+func (c *Calendar) IsWorkday(d time.Time) bool {
+	d = Day(d)
+	if c.Workdays[d] {
+		return true
+	}
+	return !c.Weekend[d.Weekday()] && !c.Holidays[d]
+}
+
+// Возвращает ближайший рабочий день, начиная с d, в направлении step (1 - вперед, -1 - назад).
+// Если рабочего дня не нашлось, возвращает d.
+func (c *Calendar) shift(d time.Time, step int) time.Time {
+	for i, cur := 0, d; i < maxHolidayRun; i, cur = i+1, cur.AddDate(0, 0, step) {
+		if c.IsWorkday(cur) {
+			return cur
+		}
+	}
+	return d
+}
+
+// Загружает календарь из файла .ics (праздники - события VEVENT) или JSON.
+// JSON - это список дат праздников либо объект
+// {"weekend": [6, 0], "holidays": ["20240101", ...], "workdays": ["20240427", ...]}.
+func LoadCalendar(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		return parseICS(data)
+	}
+	return parseCalendarJSON(data)
+}
+
+func parseCalendarJSON(data []byte) (*Calendar, error) {
+	var file struct {
+		Weekend  *[]int   `json:"weekend"`
+		Holidays []string `json:"holidays"`
+		Workdays []string `json:"workdays"`
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(data, &file.Holidays)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении календаря: %v", err)
+		}
+	} else if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении календаря: %v", err)
+	}
+
+	c := NewCalendar()
+	if file.Weekend != nil {
+		c.Weekend = make(map[time.Weekday]bool)
+		for _, wd := range *file.Weekend {
+			if wd < 0 || wd > 7 {
+				return nil, fmt.Errorf("неверный день недели в календаре: %d", wd)
+			}
+			c.Weekend[time.Weekday(wd%7)] = true
+		}
+	}
+	for _, v := range file.Holidays {
+		d, err := parseCalendarDate(v)
+		if err != nil {
+			return nil, err
+		}
+		c.Holidays[d] = true
+	}
+	for _, v := range file.Workdays {
+		d, err := parseCalendarDate(v)
+		if err != nil {
+			return nil, err
+		}
+		c.Workdays[d] = true
+	}
+	return c, nil
+}
+
+// Разбирает праздники из календаря iCalendar: каждое событие занимает дни от DTSTART до DTEND (не включая).
+func parseICS(data []byte) (*Calendar, error) {
+	// Длинные строки iCalendar переносятся, продолжение начинается с пробела или табуляции
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	c := NewCalendar()
+	var inEvent bool
+	var start, end time.Time
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent, start, end = true, time.Time{}, time.Time{}
+		case name == "END" && value == "VEVENT":
+			if start.IsZero() {
+				return nil, fmt.Errorf("событие календаря без DTSTART")
+			}
+			c.Holidays[start] = true
+			for d := start.AddDate(0, 0, 1); d.Before(end); d = d.AddDate(0, 0, 1) {
+				c.Holidays[d] = true
+			}
+			inEvent = false
+		case inEvent && (name == "DTSTART" || name == "DTEND"):
+			d, err := parseCalendarDate(value)
+			if err != nil {
+				return nil, err
+			}
+			if name == "DTSTART" {
+				start = d
+			} else {
+				end = d
+			}
+		}
+	}
+	return c, nil
+}
+
+// Разбирает дату календаря: 20240101, 20240101T000000Z или 2024-01-01.
+func parseCalendarDate(s string) (time.Time, error) {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "T")
+	for _, layout := range []string{DateFormat, ISODateFormat} {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неверная дата в календаре: %s", s)
+}
@@ -0,0 +1,87 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// Источник текущего времени. Позволяет подменять "сейчас" в тестах и в /api/nextdate.
+// Часовой пояс возвращаемого времени - пояс пользователя: по нему определяются текущий день
+// и показания часов, с которыми сравниваются даты и время задач.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().In(defaultLocation)
+}
+
+// Часы, возвращающие системное время в часовом поясе, установленном SetLocation.
+var SystemClock Clock = systemClock{}
+
+var defaultLocation = time.Local
+
+// Устанавливает часовой пояс по умолчанию для SystemClock. Вызывается при запуске сервера;
+// nil возвращает локальный пояс сервера.
+func SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.Local
+	}
+	defaultLocation = loc
+}
+
+// Возвращает часовой пояс по умолчанию.
+func Location() *time.Location {
+	return defaultLocation
+}
+
+// Часы, переводящие время других часов в заданный часовой пояс.
+type locationClock struct {
+	clock Clock
+	loc   *time.Location
+}
+
+func (c locationClock) Now() time.Time {
+	return c.clock.Now().In(c.loc)
+}
+
+// Возвращает часы, показывающие время часов c в часовом поясе loc.
+func InLocation(c Clock, loc *time.Location) Clock {
+	if lc, ok := c.(locationClock); ok {
+		c = lc.clock
+	}
+	return locationClock{clock: c, loc: loc}
+}
+
+// Загружает часовой пояс по имени из базы IANA (Europe/Moscow) или смещению от UTC (+03:00).
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return loc, nil
+	}
+	if t, err := time.Parse("-07:00", name); err == nil {
+		_, offset := t.Zone()
+		return time.FixedZone(name, offset), nil
+	}
+	return nil, fmt.Errorf("неизвестный часовой пояс: %s", name)
+}
+
+// Часы, всегда возвращающие одно и то же время.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// Возвращает полночь календарного дня, в который попадает t.
+// Все даты задач хранятся без времени, поэтому сравнения ведутся по дням.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Возвращает показания часов t с точностью до минуты в виде времени UTC.
+// Время задач хранится без часового пояса, поэтому сравнения ведутся по показаниям часов.
+func Wall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
@@ -0,0 +1,134 @@
+package task
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Ошибка разбора даты задачи.
+var ErrInvalidDate = errors.New("неверная дата")
+
+// Формат ISO 8601, в котором дата задачи принимается наряду с DateFormat.
+const ISODateFormat = "2006-01-02"
+
+// Дата задачи без времени. Нулевое значение означает, что дата не указана.
+// В JSON и в БД записывается в формате DateFormat (20060102), а при чтении
+// принимается также в формате ISO 8601 (2006-01-02).
+type Date struct {
+	t time.Time
+}
+
+// Возвращает дату дня, в который попадает t.
+func DateOf(t time.Time) Date {
+	return Date{Day(t)}
+}
+
+// Разбирает дату в формате 20060102 или 2006-01-02.
+func ParseDate(s string) (Date, error) {
+	for _, layout := range []string{DateFormat, ISODateFormat} {
+		if d, err := time.Parse(layout, s); err == nil {
+			return Date{d}, nil
+		}
+	}
+	return Date{}, fmt.Errorf("%w: %q", ErrInvalidDate, s)
+}
+
+// Возвращает полночь даты в UTC.
+func (d Date) Time() time.Time {
+	return d.t
+}
+
+// Возвращает полночь даты в часовом поясе loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.t.Year(), d.t.Month(), d.t.Day(), 0, 0, 0, 0, loc)
+}
+
+// Сообщает, что дата не указана.
+func (d Date) IsZero() bool {
+	return d.t.IsZero()
+}
+
+// Сообщает, что дата d раньше u.
+func (d Date) Before(u Date) bool {
+	return d.t.Before(u.t)
+}
+
+// Сообщает, что дата d позже u.
+func (d Date) After(u Date) bool {
+	return d.t.After(u.t)
+}
+
+// Сообщает, что d и u - одна и та же дата.
+func (d Date) Equal(u Date) bool {
+	return d.t.Equal(u.t)
+}
+
+// Сортирует даты по возрастанию.
+func SortDates(dates []Date) {
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+}
+
+// Возвращает дату в формате DateFormat или пустую строку для нулевой даты.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.t.Format(DateFormat)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Принимает строку в формате 20060102 или 2006-01-02; пустая строка и null дают нулевую дату.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDate, data)
+	}
+	if s == nil || *s == "" {
+		*d = Date{}
+		return nil
+	}
+	date, err := ParseDate(*s)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+// Записывает дату в БД строкой в формате DateFormat.
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Считывает дату из БД; NULL и пустая строка дают нулевую дату.
+func (d *Date) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("%w: %v", ErrInvalidDate, src)
+	}
+	if s == "" {
+		*d = Date{}
+		return nil
+	}
+	date, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
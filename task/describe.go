@@ -0,0 +1,259 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Дни недели в винительном падеже ("в понедельник") и в дательном множественном ("по понедельникам").
+var (
+	weekdayAcc = [...]string{"воскресенье", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу"}
+	weekdayDat = [...]string{"воскресеньям", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам"}
+)
+
+// Месяцы в родительном падеже ("25 декабря") и в предложном ("в декабре").
+var (
+	monthGen = [...]string{"", "января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	monthPrep = [...]string{"", "январе", "феврале", "марте", "апреле", "мае", "июне",
+		"июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+)
+
+// Порядковые числительные в винительном падеже для мужского, женского и среднего рода.
+var ordinalAcc = map[int][3]string{
+	1:  {"первый", "первую", "первое"},
+	2:  {"второй", "вторую", "второе"},
+	3:  {"третий", "третью", "третье"},
+	4:  {"четвертый", "четвертую", "четвертое"},
+	5:  {"пятый", "пятую", "пятое"},
+	-1: {"последний", "последнюю", "последнее"},
+	-2: {"предпоследний", "предпоследнюю", "предпоследнее"},
+}
+
+// Языки описаний правил повторения.
+const (
+	LangRu = "ru"
+	LangEn = "en"
+)
+
+// Возвращает описание правила повторения на языке lang (LangRu или LangEn, по умолчанию русский).
+func (r Rule) Describe(lang string) string {
+	if lang == LangEn {
+		return r.describeEn()
+	}
+	return r.describeRu()
+}
+
+// Возвращает описание правила повторения на русском языке, например
+// "каждый месяц во второй вторник" или "раз в 2 недели по понедельникам и пятницам".
+func (r Rule) describeRu() string {
+	var parts []string
+
+	switch r.Freq {
+	case FreqNone:
+		return "не повторяется"
+
+	case FreqDaily:
+		if r.Workday == WorkdayOnly {
+			parts = append(parts, every(r.Interval, "каждый рабочий день", "рабочий день", "рабочих дня", "рабочих дней"))
+		} else {
+			parts = append(parts, every(r.Interval, "каждый день", "день", "дня", "дней"))
+		}
+		if len(r.ByDay) > 0 {
+			parts = append(parts, "по "+weekdaysDat(r.ByDay))
+		}
+		if len(r.ByMonthDay) > 0 {
+			parts = append(parts, monthDays(r.ByMonthDay))
+		}
+
+	case FreqHourly:
+		parts = append(parts, every(r.Interval, "каждый час", "час", "часа", "часов"))
+
+	case FreqMinutely:
+		parts = append(parts, every(r.Interval, "каждую минуту", "минуту", "минуты", "минут"))
+
+	case FreqWeekly:
+		parts = append(parts, every(r.Interval, "каждую неделю", "неделю", "недели", "недель"))
+		if len(r.ByDay) > 0 {
+			parts = append(parts, "по "+weekdaysDat(r.ByDay))
+		}
+
+	case FreqMonthly:
+		parts = append(parts, every(r.Interval, "каждый месяц", "месяц", "месяца", "месяцев"))
+		if len(r.ByMonthDay) > 0 {
+			parts = append(parts, monthDays(r.ByMonthDay))
+		}
+		if len(r.ByDay) > 0 {
+			parts = append(parts, weekdaysOrdinal(r.ByDay))
+		}
+
+	case FreqYearly:
+		parts = append(parts, every(r.Interval, "каждый год", "год", "года", "лет"))
+		switch {
+		case len(r.ByMonthDate) > 0:
+			var dates []string
+			for _, md := range r.ByMonthDate {
+				dates = append(dates, strconv.Itoa(md.Day)+" "+monthGen[md.Month])
+			}
+			parts = append(parts, joinList(dates, "и"))
+		case len(r.ByMonth) > 0 && len(r.ByMonthDay) > 0 && len(r.ByDay) == 0 && allPositive(r.ByMonthDay):
+			var dates []string
+			for _, m := range r.ByMonth {
+				for _, d := range r.ByMonthDay {
+					dates = append(dates, strconv.Itoa(d)+" "+monthGen[m])
+				}
+			}
+			parts = append(parts, joinList(dates, "и"))
+			r.ByMonth = nil
+		case len(r.ByMonthDay) > 0:
+			parts = append(parts, monthDays(r.ByMonthDay))
+		}
+		if len(r.ByDay) > 0 {
+			parts = append(parts, weekdaysOrdinal(r.ByDay))
+		}
+	}
+
+	if len(r.ByMonth) > 0 {
+		var months []string
+		for _, m := range r.ByMonth {
+			months = append(months, monthPrep[m])
+		}
+		parts = append(parts, "в "+joinList(months, "и"))
+	}
+	if len(r.BySetPos) > 0 {
+		var pos []string
+		for _, p := range r.BySetPos {
+			pos = append(pos, strconv.Itoa(p))
+		}
+		parts = append(parts, "(только даты № "+strings.Join(pos, ", ")+" в каждом периоде)")
+	}
+	switch {
+	case r.Workday == WorkdayOnly && r.Freq != FreqDaily:
+		parts = append(parts, "только по рабочим дням")
+	case r.Workday == WorkdayNext:
+		parts = append(parts, "с переносом на следующий рабочий день")
+	case r.Workday == WorkdayPrev:
+		parts = append(parts, "с переносом на предыдущий рабочий день")
+	}
+	if r.Count > 0 {
+		parts = append(parts, timesText(r.Count, LangRu))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "до "+r.Until.Format("02.01.2006"))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Возвращает "каждый день" для интервала 1 или "раз в N дней".
+func every(n int, one, form1, form2, form5 string) string {
+	if n == 1 {
+		return one
+	}
+	return fmt.Sprintf("раз в %d %s", n, plural(n, form1, form2, form5))
+}
+
+// Выбирает форму слова для числа n: 1 день, 2 дня, 5 дней.
+func plural(n int, form1, form2, form5 string) string {
+	n %= 100
+	if n >= 11 && n <= 14 {
+		return form5
+	}
+	switch n % 10 {
+	case 1:
+		return form1
+	case 2, 3, 4:
+		return form2
+	default:
+		return form5
+	}
+}
+
+// Возвращает "1-го, 15-го и последнего числа".
+func monthDays(days []int) string {
+	var res []string
+	for _, d := range days {
+		switch {
+		case d == -1:
+			res = append(res, "последнего")
+		case d == -2:
+			res = append(res, "предпоследнего")
+		case d < 0:
+			res = append(res, fmt.Sprintf("%d-го с конца", -d))
+		default:
+			res = append(res, fmt.Sprintf("%d-го", d))
+		}
+	}
+	return joinList(res, "и") + " числа"
+}
+
+// Возвращает "понедельникам и средам".
+func weekdaysDat(days []WeekdayNum) string {
+	var res []string
+	for _, wd := range days {
+		res = append(res, weekdayDat[wd.Weekday])
+	}
+	return joinList(res, "и")
+}
+
+// Возвращает "во второй вторник и в последнюю пятницу" или "в каждый понедельник".
+func weekdaysOrdinal(days []WeekdayNum) string {
+	var res []string
+	for _, wd := range days {
+		gender := 0
+		switch wd.Weekday {
+		case time.Wednesday, time.Friday, time.Saturday:
+			gender = 1
+		case time.Sunday:
+			gender = 2
+		}
+
+		var ord string
+		if forms, ok := ordinalAcc[wd.N]; ok {
+			ord = forms[gender]
+		} else if wd.N == 0 {
+			ord = [3]string{"каждый", "каждую", "каждое"}[gender]
+		} else if wd.N > 0 {
+			ord = fmt.Sprintf("%d-%s", wd.N, [3]string{"й", "ю", "е"}[gender])
+		} else {
+			ord = fmt.Sprintf("%d-%s с конца", -wd.N, [3]string{"й", "ю", "е"}[gender])
+		}
+
+		prep := "в "
+		if strings.HasPrefix(ord, "вт") {
+			prep = "во "
+		}
+		res = append(res, prep+ord+" "+weekdayAcc[wd.Weekday])
+	}
+	return joinList(res, "и")
+}
+
+// Объединяет элементы через запятую, последний - через союз and ("и" или "and").
+func joinList(items []string, and string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + and + " " + items[len(items)-1]
+}
+
+func allPositive(nums []int) bool {
+	for _, n := range nums {
+		if n < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Возвращает "3 раза" или "3 times".
+func timesText(n int, lang string) string {
+	if lang == LangEn {
+		if n == 1 {
+			return "once"
+		}
+		return fmt.Sprintf("%d times", n)
+	}
+	return fmt.Sprintf("%d %s", n, plural(n, "раз", "раза", "раз"))
+}
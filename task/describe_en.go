@@ -0,0 +1,176 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Возвращает описание правила повторения на английском языке, например
+// "every month on the 1st, 31st and last day" или "every 2 weeks on Monday and Friday".
+func (r Rule) describeEn() string {
+	var parts []string
+
+	switch r.Freq {
+	case FreqNone:
+		return "does not repeat"
+
+	case FreqDaily:
+		if r.Workday == WorkdayOnly {
+			parts = append(parts, everyEn(r.Interval, "business day"))
+		} else {
+			parts = append(parts, everyEn(r.Interval, "day"))
+		}
+		if len(r.ByDay) > 0 {
+			parts = append(parts, "on "+weekdaysEn(r.ByDay))
+		}
+		if len(r.ByMonthDay) > 0 {
+			parts = append(parts, "on "+monthDaysEn(r.ByMonthDay))
+		}
+
+	case FreqHourly:
+		parts = append(parts, everyEn(r.Interval, "hour"))
+
+	case FreqMinutely:
+		parts = append(parts, everyEn(r.Interval, "minute"))
+
+	case FreqWeekly:
+		parts = append(parts, everyEn(r.Interval, "week"))
+		if len(r.ByDay) > 0 {
+			parts = append(parts, "on "+weekdaysEn(r.ByDay))
+		}
+
+	case FreqMonthly:
+		parts = append(parts, everyEn(r.Interval, "month"))
+		if len(r.ByMonthDay) > 0 {
+			parts = append(parts, "on "+monthDaysEn(r.ByMonthDay))
+		}
+		if len(r.ByDay) > 0 {
+			parts = append(parts, "on "+weekdaysEn(r.ByDay))
+		}
+
+	case FreqYearly:
+		parts = append(parts, everyEn(r.Interval, "year"))
+		switch {
+		case len(r.ByMonthDate) > 0:
+			var dates []string
+			for _, md := range r.ByMonthDate {
+				dates = append(dates, md.Month.String()+" "+strconv.Itoa(md.Day))
+			}
+			parts = append(parts, "on "+joinList(dates, "and"))
+		case len(r.ByMonth) > 0 && len(r.ByMonthDay) > 0 && len(r.ByDay) == 0 && allPositive(r.ByMonthDay):
+			var dates []string
+			for _, m := range r.ByMonth {
+				for _, d := range r.ByMonthDay {
+					dates = append(dates, m.String()+" "+strconv.Itoa(d))
+				}
+			}
+			parts = append(parts, "on "+joinList(dates, "and"))
+			r.ByMonth = nil
+		case len(r.ByMonthDay) > 0:
+			parts = append(parts, "on "+monthDaysEn(r.ByMonthDay))
+		}
+		if len(r.ByDay) > 0 {
+			parts = append(parts, "on "+weekdaysEn(r.ByDay))
+		}
+	}
+
+	if len(r.ByMonth) > 0 {
+		var months []string
+		for _, m := range r.ByMonth {
+			months = append(months, m.String())
+		}
+		parts = append(parts, "in "+joinList(months, "and"))
+	}
+	if len(r.BySetPos) > 0 {
+		var pos []string
+		for _, p := range r.BySetPos {
+			pos = append(pos, strconv.Itoa(p))
+		}
+		parts = append(parts, "(only dates no. "+strings.Join(pos, ", ")+" of each period)")
+	}
+	switch {
+	case r.Workday == WorkdayOnly && r.Freq != FreqDaily:
+		parts = append(parts, "on business days only")
+	case r.Workday == WorkdayNext:
+		parts = append(parts, "moved to the next business day")
+	case r.Workday == WorkdayPrev:
+		parts = append(parts, "moved to the previous business day")
+	}
+	if r.Count > 0 {
+		parts = append(parts, timesText(r.Count, LangEn))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "until "+r.Until.Format("2006-01-02"))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Возвращает "every day" для интервала 1 или "every 3 days".
+func everyEn(n int, unit string) string {
+	if n == 1 {
+		return "every " + unit
+	}
+	return fmt.Sprintf("every %d %ss", n, unit)
+}
+
+// Возвращает "the 1st, 31st and last day".
+func monthDaysEn(days []int) string {
+	var res []string
+	for _, d := range days {
+		switch {
+		case d == -1:
+			res = append(res, "last")
+		case d == -2:
+			res = append(res, "second to last")
+		case d < 0:
+			res = append(res, ordinalEn(-d)+" to last")
+		default:
+			res = append(res, ordinalEn(d))
+		}
+	}
+	return "the " + joinList(res, "and") + " day"
+}
+
+// Возвращает "Monday and Wednesday" или "the second Tuesday and the last Friday".
+func weekdaysEn(days []WeekdayNum) string {
+	var res []string
+	for _, wd := range days {
+		switch {
+		case wd.N == 0:
+			res = append(res, wd.Weekday.String())
+		case wd.N == -1:
+			res = append(res, "the last "+wd.Weekday.String())
+		case wd.N < 0:
+			res = append(res, "the "+ordinalWordEn(-wd.N)+" to last "+wd.Weekday.String())
+		default:
+			res = append(res, "the "+ordinalWordEn(wd.N)+" "+wd.Weekday.String())
+		}
+	}
+	return joinList(res, "and")
+}
+
+// Возвращает порядковое числительное словом до пятого включительно: second, fifth, 6th.
+func ordinalWordEn(n int) string {
+	if n >= 1 && n <= 5 {
+		return [...]string{"first", "second", "third", "fourth", "fifth"}[n-1]
+	}
+	return ordinalEn(n)
+}
+
+// Возвращает порядковое числительное: 1st, 2nd, 3rd, 11th, 22nd.
+func ordinalEn(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
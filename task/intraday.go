@@ -0,0 +1,128 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// Наибольшие интервалы для h N и min N: неделя в часах и сутки в минутах.
+const (
+	maxHours   = 168
+	maxMinutes = 1440
+)
+
+// Сколько повторений внутри дня не больше возвращает Between без ограничения limit.
+const maxIntradayDates = 10000
+
+// Формат времени задачи.
+const TimeFormat = "15:04"
+
+// Формат даты со временем для задач со временем и правил h N, min N.
+const DateTimeFormat = "20060102 15:04"
+
+// Сообщает, повторяется ли задача внутри дня (h N, min N).
+func (r Rule) intraday() bool {
+	return r.Freq == FreqHourly || r.Freq == FreqMinutely
+}
+
+// Возвращает интервал между повторениями внутри дня.
+func (r Rule) step() time.Duration {
+	if r.Freq == FreqHourly {
+		return time.Duration(r.Interval) * time.Hour
+	}
+	return time.Duration(r.Interval) * time.Minute
+}
+
+// Возвращает момент t с точностью до минуты в часовом поясе правила. Время в другом поясе
+// считается показаниями часов: так сохраняется различие между повторяющимися часами
+// при переходе на зимнее время, если t уже задано в поясе правила.
+func (r Rule) instant(t time.Time) time.Time {
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	if t.Location() == loc {
+		return t.Truncate(time.Minute)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+}
+
+// Возвращает до n повторений серии, начатой в момент start, которые позже и start, и now.
+// Повторения в исключенные дни пропускаются, после дня Until серия заканчивается.
+func (r Rule) nextIntraday(start, now time.Time, n int) ([]time.Time, error) {
+	start, after := r.instant(Wall(start)), r.instant(now)
+	if start.After(after) {
+		after = start
+	}
+
+	step := r.step()
+	var res []time.Time
+	for d := start.Add((after.Sub(start)/step + 1) * step); len(res) < n; d = d.Add(step) {
+		if !r.Until.IsZero() && Day(d).After(r.Until) {
+			break
+		}
+		if containsDay(r.Exclude, Day(d)) {
+			continue
+		}
+		res = append(res, Wall(d))
+	}
+	if len(res) == 0 {
+		return nil, ErrSeriesEnded
+	}
+	return res, nil
+}
+
+// Возвращает повторения серии, начатой в момент start, начиная с момента from и до конца дня to,
+// но не больше limit первых или, если last, последних (без limit - не больше maxIntradayDates первых).
+func (r Rule) betweenIntraday(start, from, to time.Time, limit int, last bool) []time.Time {
+	start, from, end := r.instant(Wall(start)), r.instant(from), r.instant(Day(to).AddDate(0, 0, 1))
+	if from.Before(start) {
+		from = start
+	}
+
+	step := r.step()
+	var res []time.Time
+	for d := start.Add((from.Sub(start) + step - 1) / step * step); d.Before(end); d = d.Add(step) {
+		if !r.Until.IsZero() && Day(d).After(r.Until) {
+			break
+		}
+		if containsDay(r.Exclude, Day(d)) {
+			continue
+		}
+		res = append(res, Wall(d))
+		if limit > 0 && len(res) > limit {
+			res = res[1:]
+		}
+		if (limit == 0 && len(res) == maxIntradayDates) || (limit > 0 && !last && len(res) == limit) {
+			break
+		}
+	}
+	return res
+}
+
+// Разбирает дату 20240126 или дату со временем 20240126 09:30 (также 20240126T09:30)
+// в часовом поясе loc. Момент с указанным поясом (RFC 3339, 2024-01-26T09:30:00Z)
+// переводится в пояс loc.
+func ParseDateTime(s string, loc *time.Location) (time.Time, error) {
+	if d, err := time.Parse(time.RFC3339, s); err == nil {
+		return d.In(loc), nil
+	}
+	for _, layout := range []string{DateFormat, DateTimeFormat, "20060102T15:04"} {
+		if d, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неверная дата: %s", s)
+}
+
+// Разбирает время задачи вида 09:30 и возвращает его как смещение от полуночи.
+func parseClock(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	clock, err := time.Parse(TimeFormat, s)
+	if err != nil {
+		return 0, fmt.Errorf("неверное время: %s", s)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
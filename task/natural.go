@@ -0,0 +1,453 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Слова, задающие частоту повторения вместе с необязательным числом перед ними: "every 2 weeks", "раз в 3 дня".
+var naturalUnits = map[string]Freq{
+	"day": FreqDaily, "days": FreqDaily, "день": FreqDaily, "дня": FreqDaily, "дней": FreqDaily, "дням": FreqDaily,
+	"week": FreqWeekly, "weeks": FreqWeekly, "неделя": FreqWeekly, "неделю": FreqWeekly, "недели": FreqWeekly, "недель": FreqWeekly,
+	"month": FreqMonthly, "months": FreqMonthly, "месяц": FreqMonthly, "месяца": FreqMonthly, "месяцев": FreqMonthly,
+	"year": FreqYearly, "years": FreqYearly, "год": FreqYearly, "года": FreqYearly, "лет": FreqYearly,
+	"hour": FreqHourly, "hours": FreqHourly, "час": FreqHourly, "часа": FreqHourly, "часов": FreqHourly,
+	"minute": FreqMinutely, "minutes": FreqMinutely, "min": FreqMinutely, "mins": FreqMinutely,
+	"минуту": FreqMinutely, "минуты": FreqMinutely, "минут": FreqMinutely,
+}
+
+// Наречия частоты: "daily", "ежемесячно".
+var naturalAdverbs = map[string]Freq{
+	"daily": FreqDaily, "ежедневно": FreqDaily,
+	"weekly": FreqWeekly, "еженедельно": FreqWeekly,
+	"monthly": FreqMonthly, "ежемесячно": FreqMonthly,
+	"yearly": FreqYearly, "annually": FreqYearly, "ежегодно": FreqYearly,
+	"hourly": FreqHourly, "ежечасно": FreqHourly,
+}
+
+// Слова, которые не меняют смысла правила.
+var naturalFillers = map[string]bool{
+	"every": true, "each": true, "on": true, "the": true, "of": true, "and": true, "at": true, "in": true,
+	"a": true, "an": true, "once": true, "&": true,
+	"каждый": true, "каждую": true, "каждое": true, "каждые": true, "каждого": true, "каждой": true,
+	"раз": true, "в": true, "во": true, "по": true, "на": true, "и": true,
+}
+
+// Слова после номера дня месяца: "15-го числа", "last day".
+var naturalDayWords = map[string]bool{"число": true, "числа": true, "числам": true}
+
+// Порядковые числительные: "second tuesday", "последнюю пятницу".
+var naturalOrdinals = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "last": -1, "penultimate": -2,
+}
+
+// Основы русских порядковых числительных и их окончания. "предпоследн" проверяется раньше "последн".
+var naturalOrdinalStemsRu = []struct {
+	stem string
+	n    int
+}{
+	{"перв", 1}, {"втор", 2}, {"трет", 3}, {"четверт", 4}, {"пят", 5}, {"предпоследн", -2}, {"последн", -1},
+}
+
+var naturalOrdinalEndingsRu = map[string]bool{
+	"ый": true, "ий": true, "ой": true, "ая": true, "яя": true, "ую": true, "юю": true, "ое": true, "ее": true,
+	"ого": true, "его": true, "ей": true, "ым": true, "им": true, "ья": true, "ью": true, "ье": true, "ьего": true,
+}
+
+// Основы русских названий дней недели и их сокращения.
+var naturalWeekdaysRu = []struct {
+	stem string
+	wd   time.Weekday
+}{
+	{"понедельн", time.Monday}, {"вторник", time.Tuesday}, {"сред", time.Wednesday}, {"четверг", time.Thursday},
+	{"пятниц", time.Friday}, {"суббот", time.Saturday}, {"воскресен", time.Sunday},
+}
+
+var naturalWeekdaysRuShort = map[string]time.Weekday{
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+}
+
+// Основы русских названий месяцев. Май склоняется не по основе, поэтому перечислен полностью.
+var naturalMonthsRu = []struct {
+	stem string
+	m    time.Month
+}{
+	{"январ", time.January}, {"феврал", time.February}, {"март", time.March}, {"апрел", time.April},
+	{"июн", time.June}, {"июл", time.July}, {"август", time.August}, {"сентябр", time.September},
+	{"октябр", time.October}, {"ноябр", time.November}, {"декабр", time.December},
+}
+
+var naturalMayRu = map[string]bool{"май": true, "мая": true, "мае": true}
+
+// Слова перед единицей "день", означающие рабочие дни: "every 3 business days", "по рабочим дням".
+var naturalBusiness = map[string]bool{"business": true, "working": true,
+	"рабочий": true, "рабочие": true, "рабочим": true, "рабочих": true, "рабочего": true, "рабочую": true}
+
+// Группы дней недели: будни и выходные.
+var (
+	naturalWorkdays = map[string]bool{"weekday": true, "weekdays": true, "workday": true, "workdays": true,
+		"будни": true, "будням": true, "будние": true, "будний": true}
+	naturalWeekends = map[string]bool{"weekend": true, "weekends": true,
+		"выходные": true, "выходным": true, "выходной": true}
+)
+
+// Разбирает правило повторения, записанное обычной фразой на английском или русском языке,
+// например "every 2 weeks on mon, wed", "every last friday", "yearly on dec 25" или "каждый понедельник".
+// Возвращает правило в каноническом виде: короткий синтаксис, если правило в нем выражается, иначе RRULE.
+func ParseNatural(phrase string) (Rule, error) {
+	tokens := naturalTokens(phrase)
+	if len(tokens) == 0 {
+		return Rule{}, fmt.Errorf("пустое правило повторения")
+	}
+
+	rule := newRule(FreqNone)
+	interval := 0      // число перед единицей частоты: "every 2 weeks"
+	var ordinals []int // порядковые номера, ожидающие дня недели: "first and third monday"
+	business := false  // перед единицей "день" стояло "business" или "рабочий"
+
+	// Порядковые номера без дня недели после них означают дни месяца: "on the 1st and 15th"
+	flush := func() {
+		rule.ByMonthDay = append(rule.ByMonthDay, ordinals...)
+		ordinals = nil
+	}
+	unknown := func(tok string) (Rule, error) {
+		return Rule{}, fmt.Errorf("не удалось разобрать правило повторения %q: непонятное слово %q", phrase, tok)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+
+		if n, ordinal, ok := naturalNumber(tok); ok {
+			switch {
+			case ordinal:
+				ordinals = append(ordinals, n)
+			case naturalUnits[next] != FreqNone,
+				naturalBusiness[next] && i+2 < len(tokens) && naturalUnits[tokens[i+2]] == FreqDaily:
+				interval = n
+			default:
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+			continue
+		}
+
+		if wd, ok := naturalWeekday(tok); ok {
+			if len(ordinals) == 0 {
+				rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: wd})
+			}
+			for _, n := range ordinals {
+				rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: wd, N: n})
+			}
+			ordinals = nil
+			continue
+		}
+
+		if n, ok := naturalOrdinal(tok); ok {
+			// "second to last" - предпоследний
+			if next == "to" && i+2 < len(tokens) && tokens[i+2] == "last" {
+				n = -n
+				i += 2
+			}
+			ordinals = append(ordinals, n)
+			continue
+		}
+
+		if m, ok := naturalMonth(tok); ok {
+			flush()
+			rule.ByMonth = append(rule.ByMonth, m)
+			continue
+		}
+
+		switch {
+		case naturalFillers[tok]:
+			continue
+
+		case naturalDayWords[tok]:
+			flush()
+			continue
+
+		case naturalBusiness[tok]:
+			business = true
+			continue
+
+		case naturalWorkdays[tok] || naturalWeekends[tok]:
+			if len(ordinals) > 0 {
+				return unknown(tok)
+			}
+			days := []time.Weekday{time.Saturday, time.Sunday}
+			if naturalWorkdays[tok] {
+				days = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+			}
+			for _, wd := range days {
+				rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: wd})
+			}
+			continue
+
+		case tok == "other" || tok == "через":
+			interval = 2
+			continue
+		}
+
+		freq, isUnit := naturalUnits[tok]
+		if !isUnit {
+			freq, isUnit = naturalAdverbs[tok]
+			if isUnit && interval > 0 {
+				return unknown(tok)
+			}
+		}
+		if !isUnit {
+			return unknown(tok)
+		}
+		// "last day", "15th day" - день месяца, а не частота
+		if freq == FreqDaily && interval == 0 && (len(ordinals) > 0 || (i > 0 && isNaturalNumber(tokens[i-1]))) {
+			// "first business day" - 1-е число с переносом вперед, "last business day" - последнее с переносом назад
+			if business && len(ordinals) > 0 {
+				rule.Workday = WorkdayNext
+				if ordinals[0] < 0 {
+					rule.Workday = WorkdayPrev
+				}
+				business = false
+			}
+			flush()
+			continue
+		}
+		if business {
+			if freq != FreqDaily {
+				return unknown(tok)
+			}
+			rule.Workday = WorkdayOnly
+			business = false
+		}
+		if rule.Freq != FreqNone && rule.Freq != freq {
+			return Rule{}, fmt.Errorf("в правиле повторения %q указано несколько частот", phrase)
+		}
+		rule.Freq = freq
+		if interval > 0 {
+			rule.Interval = interval
+			interval = 0
+		}
+	}
+	flush()
+
+	if interval > 0 {
+		return Rule{}, fmt.Errorf("в правиле повторения %q не указано, в чем измеряется интервал", phrase)
+	}
+	if business {
+		return Rule{}, fmt.Errorf("в правиле повторения %q после слов о рабочих днях не указан день", phrase)
+	}
+	if err := rule.inferFreq(phrase); err != nil {
+		return Rule{}, err
+	}
+
+	rule.normalize()
+	rule.rrule = !rule.isShort()
+	return ParseRule(rule.String())
+}
+
+// Определяет частоту, если она не названа явно, и проверяет, что фраза описывает осмысленное правило.
+func (r *Rule) inferFreq(phrase string) error {
+	ordinal := false
+	for _, wd := range r.ByDay {
+		ordinal = ordinal || wd.N != 0
+	}
+
+	switch {
+	case r.Freq != FreqNone:
+	case ordinal || len(r.ByMonthDay) > 0:
+		r.Freq = FreqMonthly
+		if len(r.ByMonth) > 0 {
+			r.Freq = FreqYearly
+		}
+	case len(r.ByDay) > 0:
+		r.Freq = FreqWeekly
+	default:
+		return fmt.Errorf("в правиле повторения %q не указано, как часто повторять задачу", phrase)
+	}
+
+	// "каждый день по будням" - то же, что еженедельно по будним дням
+	if r.Freq == FreqDaily && r.Interval == 1 && len(r.ByDay) > 0 && !ordinal &&
+		len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		r.Freq = FreqWeekly
+	}
+
+	switch {
+	case (r.Freq == FreqDaily || r.intraday()) && (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 || len(r.ByMonth) > 0):
+		return fmt.Errorf("в правиле повторения %q дни указаны для ежедневной задачи", phrase)
+	case r.Freq == FreqWeekly && (ordinal || len(r.ByMonthDay) > 0):
+		return fmt.Errorf("в правиле повторения %q для еженедельной задачи указан номер дня", phrase)
+	case len(r.ByDay) > 0 && len(r.ByMonthDay) > 0:
+		return fmt.Errorf("в правиле повторения %q нельзя смешивать дни месяца и дни недели", phrase)
+	}
+	for _, d := range r.ByMonthDay {
+		if d == 0 || d > 31 || d < -31 {
+			return fmt.Errorf("в правиле повторения %q неверный день месяца: %d", phrase, d)
+		}
+	}
+
+	// "yearly on dec 25" - конкретные даты года, как в y 12-25
+	if r.Freq == FreqYearly && len(r.ByMonth) > 0 && len(r.ByMonthDay) > 0 && len(r.ByDay) == 0 && allPositive(r.ByMonthDay) {
+		for _, m := range r.ByMonth {
+			for _, d := range r.ByMonthDay {
+				if d > daysIn(2000, m) {
+					return fmt.Errorf("в правиле повторения %q неверная дата: %d %s", phrase, d, m)
+				}
+				r.ByMonthDate = append(r.ByMonthDate, MonthDay{Month: m, Day: d})
+			}
+		}
+		r.ByMonth, r.ByMonthDay = nil, nil
+	}
+	return nil
+}
+
+// Сообщает, выражается ли правило в коротком синтаксисе d, w, m или y.
+func (r Rule) isShort() bool {
+	if len(r.BySetPos) > 0 || r.Count > 0 || !r.Until.IsZero() || r.WeekStart != time.Monday {
+		return false
+	}
+	switch r.Freq {
+	case FreqDaily:
+		return r.Interval <= maxInterval[FreqDaily] && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0
+	case FreqWeekly:
+		if r.Interval > maxInterval[FreqWeekly] || len(r.ByDay) == 0 || len(r.ByMonthDay) > 0 || len(r.ByMonth) > 0 {
+			return false
+		}
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return false
+			}
+		}
+		return true
+	case FreqMonthly:
+		if r.Interval > maxInterval[FreqMonthly] || (len(r.ByDay) == 0) == (len(r.ByMonthDay) == 0) {
+			return false
+		}
+		for _, wd := range r.ByDay {
+			if wd.N == 0 || wd.N > 5 || wd.N < -5 {
+				return false
+			}
+		}
+		for _, d := range r.ByMonthDay {
+			if d < -2 {
+				return false
+			}
+		}
+		return true
+	case FreqHourly, FreqMinutely:
+		return true
+	case FreqYearly:
+		return r.Interval <= maxInterval[FreqYearly] && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0
+	default:
+		return false
+	}
+}
+
+// Разбивает фразу на слова в нижнем регистре. Дефисы внутри слов ("second-to-last") разделяют слова,
+// а у чисел ("15-го") остаются частью числа.
+func naturalTokens(phrase string) []string {
+	var tokens []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(phrase), func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '.' || r == '\t' || r == '\n'
+	}) {
+		if f[0] >= '0' && f[0] <= '9' {
+			tokens = append(tokens, f)
+			continue
+		}
+		for _, part := range strings.Split(f, "-") {
+			if part != "" {
+				tokens = append(tokens, part)
+			}
+		}
+	}
+	return tokens
+}
+
+// Разбирает число с необязательным окончанием порядкового числительного: 2, 1st, 15th, 15-го, 3-е.
+func naturalNumber(tok string) (n int, ordinal bool, ok bool) {
+	digits := strings.TrimLeft(tok, "0123456789")
+	numStr := tok[:len(tok)-len(digits)]
+	if numStr == "" {
+		return 0, false, false
+	}
+	switch strings.TrimPrefix(digits, "-") {
+	case "":
+		ordinal = digits != ""
+	case "st", "nd", "rd", "th", "го", "е", "й", "ю", "я", "ое", "ого", "ий":
+		ordinal = true
+	default:
+		return 0, false, false
+	}
+	n, err := strconv.Atoi(numStr)
+	if err != nil || n < 1 {
+		return 0, false, false
+	}
+	return n, ordinal, true
+}
+
+func isNaturalNumber(tok string) bool {
+	_, _, ok := naturalNumber(tok)
+	return ok
+}
+
+func naturalOrdinal(tok string) (int, bool) {
+	if n, ok := naturalOrdinals[tok]; ok {
+		return n, true
+	}
+	for _, v := range naturalOrdinalStemsRu {
+		if strings.HasPrefix(tok, v.stem) && naturalOrdinalEndingsRu[tok[len(v.stem):]] {
+			return v.n, true
+		}
+	}
+	return 0, false
+}
+
+// Распознает день недели: monday, mondays, mon, tues, понедельник, по понедельникам, пн.
+func naturalWeekday(tok string) (time.Weekday, bool) {
+	if wd, ok := naturalWeekdaysRuShort[tok]; ok {
+		return wd, true
+	}
+	for _, v := range naturalWeekdaysRu {
+		if strings.HasPrefix(tok, v.stem) {
+			return v.wd, true
+		}
+	}
+	en := tok
+	if len(en) > 3 {
+		en = strings.TrimSuffix(en, "s")
+	}
+	if len(en) < 3 {
+		return 0, false
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.HasPrefix(strings.ToLower(wd.String()), en) {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+// Распознает месяц: dec, december, декабря, в декабре.
+func naturalMonth(tok string) (time.Month, bool) {
+	if naturalMayRu[tok] {
+		return time.May, true
+	}
+	for _, v := range naturalMonthsRu {
+		if strings.HasPrefix(tok, v.stem) {
+			return v.m, true
+		}
+	}
+	if len(tok) < 3 {
+		return 0, false
+	}
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), tok) {
+			return m, true
+		}
+	}
+	return 0, false
+}
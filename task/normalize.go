@@ -0,0 +1,177 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Приводит списки правила к каноническому виду: сортирует и убирает повторы.
+// Дни месяца идут в порядке их следования в месяце: 1, 31, -2, -1.
+func (r *Rule) normalize() {
+	sort.Slice(r.ByMonthDay, func(i, j int) bool {
+		return monthDayOrder(r.ByMonthDay[i]) < monthDayOrder(r.ByMonthDay[j])
+	})
+	r.ByMonthDay = dedupInts(r.ByMonthDay)
+
+	sort.Slice(r.ByDay, func(i, j int) bool {
+		a, b := r.ByDay[i], r.ByDay[j]
+		if a.N != b.N {
+			return monthDayOrder(a.N) < monthDayOrder(b.N)
+		}
+		return a.Weekday < b.Weekday
+	})
+	byDay := r.ByDay[:0]
+	for i, wd := range r.ByDay {
+		if i == 0 || wd != r.ByDay[i-1] {
+			byDay = append(byDay, wd)
+		}
+	}
+	r.ByDay = byDay
+
+	sort.Slice(r.ByMonth, func(i, j int) bool {
+		return r.ByMonth[i] < r.ByMonth[j]
+	})
+	months := r.ByMonth[:0]
+	for i, m := range r.ByMonth {
+		if i == 0 || m != r.ByMonth[i-1] {
+			months = append(months, m)
+		}
+	}
+	r.ByMonth = months
+
+	sort.Slice(r.ByMonthDate, func(i, j int) bool {
+		a, b := r.ByMonthDate[i], r.ByMonthDate[j]
+		return a.Month < b.Month || (a.Month == b.Month && a.Day < b.Day)
+	})
+	dates := r.ByMonthDate[:0]
+	for i, md := range r.ByMonthDate {
+		if i == 0 || md != r.ByMonthDate[i-1] {
+			dates = append(dates, md)
+		}
+	}
+	r.ByMonthDate = dates
+
+	sort.Slice(r.BySetPos, func(i, j int) bool {
+		return monthDayOrder(r.BySetPos[i]) < monthDayOrder(r.BySetPos[j])
+	})
+	r.BySetPos = dedupInts(r.BySetPos)
+}
+
+// Возвращает правило в каноническом виде: RRULE остается RRULE, короткий синтаксис - коротким.
+func (r Rule) String() string {
+	if r.rrule {
+		return r.RRule()
+	}
+
+	return r.shortString() + workdaySuffix(r.Workday)
+}
+
+func (r Rule) shortString() string {
+	switch r.Freq {
+	case FreqDaily:
+		return "d " + strconv.Itoa(r.Interval)
+	case FreqHourly:
+		return "h " + strconv.Itoa(r.Interval)
+	case FreqMinutely:
+		return "min " + strconv.Itoa(r.Interval)
+	case FreqYearly:
+		if len(r.ByMonthDate) == 0 {
+			return "y" + intervalSuffix(r.Interval)
+		}
+		return "y " + joinMonthDays(r.ByMonthDate) + intervalSuffix(r.Interval)
+	case FreqWeekly:
+		var days []string
+		for _, wd := range r.ByDay {
+			days = append(days, strconv.Itoa(int(wd.Weekday)))
+		}
+		return "w " + strings.Join(days, ",") + intervalSuffix(r.Interval)
+	case FreqMonthly:
+		var days []string
+		for _, d := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(d))
+		}
+		for _, wd := range r.ByDay {
+			days = append(days, strconv.Itoa(wd.N)+":"+strconv.Itoa(int(wd.Weekday)))
+		}
+		res := "m " + strings.Join(days, ",")
+		if len(r.ByMonth) > 0 {
+			var months []string
+			for _, m := range r.ByMonth {
+				months = append(months, strconv.Itoa(int(m)))
+			}
+			res += " " + strings.Join(months, ",")
+		}
+		return res + intervalSuffix(r.Interval)
+	default:
+		return ""
+	}
+}
+
+// Возвращает repeat в каноническом виде, например "m 31,-1,1" -> "m 1,31,-1", "w 7,1" -> "w 0,1".
+// Правило, записанное обычной фразой ("every last friday"), переводится в короткий синтаксис или RRULE.
+func NormalizeRepeat(repeat string) (string, error) {
+	rule, err := ParseRule(repeat)
+	if err != nil {
+		natural, naturalErr := ParseNatural(repeat)
+		if naturalErr != nil {
+			return "", err
+		}
+		rule = natural
+	}
+	return rule.String(), nil
+}
+
+// Приводит поле Repeat задачи к каноническому виду.
+func (t *Task) Normalize() error {
+	repeat, err := NormalizeRepeat(t.Repeat)
+	if err != nil {
+		return err
+	}
+	t.Repeat = repeat
+	return nil
+}
+
+// Возвращает даты года в виде "12-25,03-31".
+func joinMonthDays(dates []MonthDay) string {
+	res := make([]string, len(dates))
+	for i, md := range dates {
+		res[i] = fmt.Sprintf("%02d-%02d", int(md.Month), md.Day)
+	}
+	return strings.Join(res, ",")
+}
+
+func workdaySuffix(workday Workday) string {
+	for suffix, v := range workdaySuffixes {
+		if v == workday {
+			return " " + suffix
+		}
+	}
+	return ""
+}
+
+func intervalSuffix(interval int) string {
+	if interval <= 1 {
+		return ""
+	}
+	return " /" + strconv.Itoa(interval)
+}
+
+// Порядок, в котором положительные номера идут раньше отрицательных: 1, 2, ..., -2, -1.
+func monthDayOrder(n int) int {
+	if n < 0 {
+		return 1000 + n
+	}
+	return n
+}
+
+func dedupInts(nums []int) []int {
+	res := nums[:0]
+	for i, n := range nums {
+		if i == 0 || n != nums[i-1] {
+			res = append(res, n)
+		}
+	}
+	return res
+}
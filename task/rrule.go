@@ -0,0 +1,293 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var rruleFreqs = map[string]Freq{
+	"DAILY":    FreqDaily,
+	"WEEKLY":   FreqWeekly,
+	"MONTHLY":  FreqMonthly,
+	"YEARLY":   FreqYearly,
+	"HOURLY":   FreqHourly,
+	"MINUTELY": FreqMinutely,
+}
+
+var rruleWorkdays = map[string]Workday{
+	"ONLY": WorkdayOnly,
+	"NEXT": WorkdayNext,
+	"PREV": WorkdayPrev,
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Разбирает тело правила RRULE (RFC 5545) без префикса "RRULE:",
+// например "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1".
+// Поддерживаются FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS, COUNT, UNTIL и WKST,
+// а также расширения X-WORKDAY=ONLY|NEXT|PREV для учета нерабочих дней
+// и X-BYMONTHDATE=12-25,03-31 для конкретных дат года. FREQ=HOURLY и FREQ=MINUTELY
+// соответствуют h N и min N и используются только с INTERVAL и UNTIL.
+func parseRRule(s string) (Rule, error) {
+	rule := newRule(FreqNone)
+	rule.rrule = true
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(strings.TrimSpace(s), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("неверный формат RRULE: %s", part)
+		}
+		if seen[key] {
+			return Rule{}, fmt.Errorf("повторяющийся параметр RRULE: %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			freq, ok := rruleFreqs[value]
+			if !ok {
+				return Rule{}, fmt.Errorf("неподдерживаемая частота RRULE: %s", value)
+			}
+			rule.Freq = freq
+		case "INTERVAL":
+			rule.Interval, err = parseRRuleInt(value, 1, 1000)
+		case "COUNT":
+			rule.Count, err = parseRRuleInt(value, 1, 100000)
+		case "UNTIL":
+			rule.Until, err = parseRRuleUntil(value)
+		case "WKST":
+			wd, ok := rruleWeekdays[value]
+			if !ok {
+				return Rule{}, fmt.Errorf("неверный день недели в RRULE: %s", value)
+			}
+			rule.WeekStart = wd
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wd, err := parseRRuleWeekday(v)
+				if err != nil {
+					return Rule{}, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := parseRRuleInt(v, -31, 31)
+				if err != nil || day == 0 {
+					return Rule{}, fmt.Errorf("неверное значение BYMONTHDAY: %s", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				month, err := parseRRuleInt(v, 1, 12)
+				if err != nil {
+					return Rule{}, err
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "X-BYMONTHDATE":
+			for _, v := range strings.Split(value, ",") {
+				date, err := parseMonthDay(v)
+				if err != nil {
+					return Rule{}, fmt.Errorf("неверное значение X-BYMONTHDATE: %v", err)
+				}
+				rule.ByMonthDate = append(rule.ByMonthDate, date)
+			}
+		case "X-WORKDAY":
+			workday, ok := rruleWorkdays[value]
+			if !ok {
+				return Rule{}, fmt.Errorf("неверное значение X-WORKDAY: %s", value)
+			}
+			rule.Workday = workday
+		case "BYSETPOS":
+			for _, v := range strings.Split(value, ",") {
+				pos, err := parseRRuleInt(v, -366, 366)
+				if err != nil || pos == 0 {
+					return Rule{}, fmt.Errorf("неверное значение BYSETPOS: %s", v)
+				}
+				rule.BySetPos = append(rule.BySetPos, pos)
+			}
+		default:
+			return Rule{}, fmt.Errorf("неподдерживаемый параметр RRULE: %s", key)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("неверное значение %s: %v", key, err)
+		}
+	}
+
+	if rule.Freq == FreqNone {
+		return Rule{}, fmt.Errorf("в RRULE не указан FREQ")
+	}
+	if max := maxInterval[rule.Freq]; rule.Interval > max {
+		return Rule{}, fmt.Errorf("неверное значение INTERVAL: значение %d вне диапазона [1, %d]", rule.Interval, max)
+	}
+	if rule.intraday() {
+		if rule.Count > 0 || len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0 || len(rule.ByMonth) > 0 ||
+			len(rule.ByMonthDate) > 0 || len(rule.BySetPos) > 0 || rule.Workday != WorkdayAny {
+			return Rule{}, fmt.Errorf("FREQ=%s используется только с INTERVAL и UNTIL", freqNames[rule.Freq])
+		}
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return Rule{}, fmt.Errorf("COUNT и UNTIL не могут использоваться вместе")
+	}
+	if len(rule.BySetPos) > 0 && len(rule.ByDay) == 0 && len(rule.ByMonthDay) == 0 && len(rule.ByMonth) == 0 {
+		return Rule{}, fmt.Errorf("BYSETPOS используется только вместе с BYDAY, BYMONTHDAY или BYMONTH")
+	}
+	if len(rule.ByMonthDate) > 0 && (rule.Freq != FreqYearly ||
+		len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0 || len(rule.ByMonth) > 0) {
+		return Rule{}, fmt.Errorf("X-BYMONTHDATE используется только с FREQ=YEARLY и без BYDAY, BYMONTHDAY и BYMONTH")
+	}
+	if rule.Freq == FreqWeekly && len(rule.ByMonthDay) > 0 {
+		return Rule{}, fmt.Errorf("BYMONTHDAY не используется с FREQ=WEEKLY")
+	}
+	for _, wd := range rule.ByDay {
+		if wd.N == 0 {
+			continue
+		}
+		if rule.Freq == FreqDaily || rule.Freq == FreqWeekly {
+			return Rule{}, fmt.Errorf("номер дня недели в BYDAY допустим только для MONTHLY и YEARLY")
+		}
+		if rule.Freq == FreqMonthly && (wd.N > 5 || wd.N < -5) {
+			return Rule{}, fmt.Errorf("неверный номер дня недели в BYDAY: %d", wd.N)
+		}
+	}
+
+	return rule, nil
+}
+
+// Разбирает элемент BYDAY вида MO, 2TU или -1FR.
+func parseRRuleWeekday(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("неверное значение BYDAY: %s", s)
+	}
+	wd, ok := rruleWeekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("неверное значение BYDAY: %s", s)
+	}
+	res := WeekdayNum{Weekday: wd}
+	if num := s[:len(s)-2]; num != "" {
+		n, err := parseRRuleInt(num, -53, 53)
+		if err != nil || n == 0 {
+			return WeekdayNum{}, fmt.Errorf("неверное значение BYDAY: %s", s)
+		}
+		res.N = n
+	}
+	return res, nil
+}
+
+// Разбирает UNTIL в виде даты (20240131 или 2024-01-31). Дата со временем (20240131T235959Z)
+// не принимается: в часовом поясе задачи такой момент может прийтись на другой день.
+func parseRRuleUntil(s string) (time.Time, error) {
+	if strings.Contains(s, "T") {
+		return time.Time{}, fmt.Errorf("ожидается дата без времени: %s", s)
+	}
+	until, err := ParseDate(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return until.Time(), nil
+}
+
+func parseRRuleInt(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+	if err != nil {
+		return 0, err
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("значение %d вне диапазона [%d, %d]", n, min, max)
+	}
+	return n, nil
+}
+
+// Возвращает правило в виде RRULE с параметрами в фиксированном порядке.
+func (r Rule) RRule() string {
+	parts := []string{"FREQ=" + freqNames[r.Freq]}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, wd := range r.ByDay {
+			day := weekdayNames[wd.Weekday]
+			if wd.N != 0 {
+				day = strconv.Itoa(wd.N) + day
+			}
+			days = append(days, day)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		var months []int
+		for _, m := range r.ByMonth {
+			months = append(months, int(m))
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	if len(r.ByMonthDate) > 0 {
+		parts = append(parts, "X-BYMONTHDATE="+joinMonthDays(r.ByMonthDate))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format(DateFormat))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	for name, workday := range rruleWorkdays {
+		if workday == r.Workday {
+			parts = append(parts, "X-WORKDAY="+name)
+		}
+	}
+	return "RRULE:" + strings.Join(parts, ";")
+}
+
+var freqNames = map[Freq]string{
+	FreqDaily:    "DAILY",
+	FreqWeekly:   "WEEKLY",
+	FreqMonthly:  "MONTHLY",
+	FreqYearly:   "YEARLY",
+	FreqHourly:   "HOURLY",
+	FreqMinutely: "MINUTELY",
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU",
+}
+
+func joinInts(nums []int) string {
+	res := make([]string, len(nums))
+	for i, n := range nums {
+		res[i] = strconv.Itoa(n)
+	}
+	return strings.Join(res, ",")
+}
@@ -0,0 +1,701 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Частота повторения. Соответствует FREQ из RFC 5545.
+type Freq int
+
+const (
+	FreqNone     Freq = iota // задача не повторяется
+	FreqDaily                // d N, FREQ=DAILY
+	FreqWeekly               // w 1,2,... [/N], FREQ=WEEKLY
+	FreqMonthly              // m 1,-1 [1,2,...] [/N] или m 2:2,-1:5 [1,2,...] [/N], FREQ=MONTHLY
+	FreqYearly               // y [12-25,03-31] [/N], FREQ=YEARLY
+	FreqHourly               // h N, каждые N часов от времени задачи
+	FreqMinutely             // min N, каждые N минут от времени задачи
+)
+
+// Через сколько лет без единой подходящей даты поиск прекращается.
+const maxGapYears = 100
+
+// Наибольший интервал повторений для каждой частоты. Одинаков для короткого синтаксиса
+// (d N, w ... /N, m ... /N, y /N, h N, min N) и для INTERVAL в RRULE.
+var maxInterval = map[Freq]int{
+	FreqDaily:    399,
+	FreqWeekly:   52,
+	FreqMonthly:  12,
+	FreqYearly:   100,
+	FreqHourly:   maxHours,
+	FreqMinutely: maxMinutes,
+}
+
+// Ошибка, возвращаемая, когда серия повторений закончилась (COUNT или UNTIL).
+var ErrSeriesEnded = errors.New("серия повторений завершена")
+
+var errNoDate = errors.New("не найдено подходящей даты для правила")
+
+// День недели с необязательным порядковым номером: 2TU - второй вторник, -1FR - последняя пятница.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int // 0 - каждый такой день недели в периоде
+}
+
+// Дата в году: {12, 25} - 25 декабря.
+type MonthDay struct {
+	Month time.Month
+	Day   int
+}
+
+// Правило повторения, полученное разбором поля repeat.
+// Короткий синтаксис (d, w, m, y) и RRULE разбираются в одно и то же представление.
+type Rule struct {
+	Freq       Freq
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int // -1 - последний день месяца, -2 - предпоследний и т.д.
+	ByMonth    []time.Month
+	BySetPos   []int
+	// Конкретные даты года (y 12-25,03-31). 29 февраля в невисокосный год переходит на 28 февраля
+	ByMonthDate []MonthDay
+	Count       int       // 0 - без ограничения
+	Until       time.Time // нулевое значение - без ограничения
+	WeekStart   time.Weekday
+	Exclude     []time.Time // исключенные даты (EXDATE), учитываются в COUNT, но пропускаются
+	Workday     Workday
+	Calendar    *Calendar // nil - календарь, установленный SetCalendar
+	// Часовой пояс, в котором h N и min N отсчитывают прошедшее время, так что при переходе
+	// на летнее время повторения сдвигаются по часам. nil - отсчет по показаниям часов (UTC)
+	Location *time.Location
+
+	rrule bool // правило задано в формате RRULE
+}
+
+// Обработка нерабочих дней. В коротком синтаксисе задается последним сегментом правила,
+// в RRULE - параметром X-WORKDAY.
+type Workday int
+
+const (
+	WorkdayAny  Workday = iota // нерабочие дни не учитываются
+	WorkdayOnly                // bd, X-WORKDAY=ONLY: только рабочие дни, d N отсчитывает N рабочих дней
+	WorkdayNext                // bd+, X-WORKDAY=NEXT: перенос на следующий рабочий день
+	WorkdayPrev                // bd-, X-WORKDAY=PREV: перенос на предыдущий рабочий день
+)
+
+var workdaySuffixes = map[string]Workday{"bd": WorkdayOnly, "bd+": WorkdayNext, "bd-": WorkdayPrev}
+
+func newRule(freq Freq) Rule {
+	return Rule{Freq: freq, Interval: 1, WeekStart: time.Monday}
+}
+
+// Разбирает строку repeat в правило повторения. Списки в правиле отсортированы и не содержат повторов.
+func ParseRule(repeat string) (Rule, error) {
+	rule, err := parseRepeat(repeat)
+	if err != nil {
+		return Rule{}, err
+	}
+	rule.normalize()
+	return rule, nil
+}
+
+func parseRepeat(repeat string) (Rule, error) {
+	if strings.HasPrefix(strings.ToUpper(repeat), "RRULE:") {
+		return parseRRule(repeat[len("RRULE:"):])
+	}
+
+	// Модификатор рабочих дней: "d 1 bd", "m 1 bd+", "m -1 /3 bd-"
+	if i := strings.LastIndex(repeat, " "); i >= 0 {
+		if workday, ok := workdaySuffixes[repeat[i+1:]]; ok {
+			rule, err := parseShort(repeat[:i])
+			if err != nil {
+				return Rule{}, err
+			}
+			if rule.Freq == FreqNone || rule.intraday() {
+				return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+			}
+			rule.Workday = workday
+			return rule, nil
+		}
+	}
+	return parseShort(repeat)
+}
+
+// Разбирает короткий синтаксис d, w, m, y без модификатора рабочих дней.
+func parseShort(repeat string) (Rule, error) {
+	switch {
+	case repeat == "":
+		return Rule{Freq: FreqNone}, nil
+
+	case repeat == "y":
+		return newRule(FreqYearly), nil
+
+	case strings.HasPrefix(repeat, "y "):
+		splitted, interval, err := cutInterval(strings.Split(repeat, " "), maxInterval[FreqYearly])
+		if err != nil || len(splitted) > 2 {
+			return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+		}
+
+		rule := newRule(FreqYearly)
+		rule.Interval = interval
+		if len(splitted) == 2 {
+			for _, s := range strings.Split(splitted[1], ",") {
+				date, err := parseMonthDay(s)
+				if err != nil {
+					return Rule{}, fmt.Errorf("неверный формат: %s; %v", repeat, err)
+				}
+				rule.ByMonthDate = append(rule.ByMonthDate, date)
+			}
+		}
+		return rule, nil
+
+	case strings.HasPrefix(repeat, "h "), strings.HasPrefix(repeat, "min "):
+		freq := FreqHourly
+		if strings.HasPrefix(repeat, "min ") {
+			freq = FreqMinutely
+		}
+		_, numStr, _ := strings.Cut(repeat, " ")
+		num, err := strconv.Atoi(numStr)
+		if err != nil || num < 1 || num > maxInterval[freq] {
+			return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+		}
+		rule := newRule(freq)
+		rule.Interval = num
+		return rule, nil
+
+	case strings.HasPrefix(repeat, "d "):
+		daysNum, err := strconv.Atoi(strings.TrimPrefix(repeat, "d "))
+		if err != nil {
+			return Rule{}, fmt.Errorf("неверный формат: %s ; %v", repeat, err)
+		}
+		if daysNum > maxInterval[FreqDaily] {
+			return Rule{}, fmt.Errorf("перенос задачи больше чем на %d дней: %s;", maxInterval[FreqDaily], repeat)
+		}
+		if daysNum < 1 {
+			return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+		}
+		rule := newRule(FreqDaily)
+		rule.Interval = daysNum
+		return rule, nil
+
+	case strings.HasPrefix(repeat, "w "):
+		splitted, interval, err := cutInterval(strings.Split(repeat, " "), maxInterval[FreqWeekly])
+		if err != nil || len(splitted) != 2 {
+			return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+		}
+
+		rule := newRule(FreqWeekly)
+		rule.Interval = interval
+		for _, s := range strings.Split(splitted[1], ",") {
+			num, err := strconv.Atoi(s)
+			if err != nil || num > 7 || num < 0 {
+				return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+			}
+			rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: time.Weekday(num % 7)})
+		}
+		return rule, nil
+
+	case strings.HasPrefix(repeat, "m "):
+		splitted, interval, err := cutInterval(strings.Split(repeat, " "), maxInterval[FreqMonthly])
+		if err != nil || len(splitted) > 3 || len(splitted) < 2 {
+			return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+		}
+
+		rule := newRule(FreqMonthly)
+		rule.Interval = interval
+		for _, s := range strings.Split(splitted[1], ",") {
+			// Порядковый день недели: 2:2 - второй вторник, -1:5 - последняя пятница
+			if ordStr, wdStr, ok := strings.Cut(s, ":"); ok {
+				ord, err := strconv.Atoi(ordStr)
+				if err != nil || ord == 0 || ord > 5 || ord < -5 {
+					return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+				}
+				wd, err := strconv.Atoi(wdStr)
+				if err != nil || wd > 7 || wd < 0 {
+					return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: time.Weekday(wd % 7), N: ord})
+				continue
+			}
+
+			dayNum, err := strconv.Atoi(s)
+			if err != nil || dayNum > 31 || dayNum == 0 || dayNum < -2 {
+				return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+			}
+			rule.ByMonthDay = append(rule.ByMonthDay, dayNum)
+		}
+		// Дни месяца и дни недели в одном правиле пересекались бы, а не объединялись
+		if len(rule.ByDay) > 0 && len(rule.ByMonthDay) > 0 {
+			return Rule{}, fmt.Errorf("неверный формат: %s; нельзя смешивать дни месяца и дни недели", repeat)
+		}
+
+		if len(splitted) == 3 {
+			for _, s := range strings.Split(splitted[2], ",") {
+				mthNum, err := strconv.Atoi(s)
+				if err != nil || mthNum > 12 || mthNum < 1 {
+					return Rule{}, fmt.Errorf("неверный формат: %s;", repeat)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(mthNum))
+			}
+		}
+		return rule, nil
+
+	default:
+		return Rule{}, fmt.Errorf("неверный формат поля 'repeat': %s", repeat)
+	}
+}
+
+// Разбирает дату в году вида MM-DD. 29 февраля допустимо, 31 апреля - нет.
+func parseMonthDay(s string) (MonthDay, error) {
+	monthStr, dayStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return MonthDay{}, fmt.Errorf("ожидается дата вида MM-DD: %s", s)
+	}
+	month, err := strconv.Atoi(monthStr)
+	if err != nil || month < 1 || month > 12 {
+		return MonthDay{}, fmt.Errorf("неверный месяц: %s", s)
+	}
+	day, err := strconv.Atoi(dayStr)
+	// Високосный 2000 год, чтобы 29 февраля считалось допустимым
+	if err != nil || day < 1 || day > daysIn(2000, time.Month(month)) {
+		return MonthDay{}, fmt.Errorf("неверный день: %s", s)
+	}
+	return MonthDay{Month: time.Month(month), Day: day}, nil
+}
+
+// Отделяет от правила необязательный последний сегмент /N - интервал в неделях или месяцах.
+// Интервал отсчитывается от даты задачи: "w 1 /2" - понедельник каждой второй недели.
+func cutInterval(splitted []string, max int) ([]string, int, error) {
+	last := splitted[len(splitted)-1]
+	if !strings.HasPrefix(last, "/") {
+		return splitted, 1, nil
+	}
+	interval, err := strconv.Atoi(strings.TrimPrefix(last, "/"))
+	if err != nil || interval < 1 || interval > max {
+		return nil, 0, fmt.Errorf("неверный интервал: %s", last)
+	}
+	return splitted[:len(splitted)-1], interval, nil
+}
+
+// Возвращает ближайшую дату серии, начатой в date, которая позже и date, и текущего дня now.
+// Если серия закончилась раньше, возвращает ErrSeriesEnded.
+func (r Rule) Next(date, now time.Time) (time.Time, error) {
+	dates, err := r.NextN(date, now, 1)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return dates[0], nil
+}
+
+// Возвращает до n ближайших дат серии, начатой в date, которые позже и date, и текущего дня now.
+// Если серия закончилась раньше первой такой даты, возвращает ErrSeriesEnded.
+func (r Rule) NextN(date, now time.Time, n int) ([]time.Time, error) {
+	if r.Freq == FreqNone {
+		return nil, fmt.Errorf("задача не повторяется")
+	}
+
+	if r.intraday() {
+		return r.nextIntraday(date, now, n)
+	}
+
+	start, after := Day(date), Day(now)
+	if start.After(after) {
+		after = start
+	}
+
+	var dates []time.Time
+	err := r.iterate(start, after, func(d time.Time) bool {
+		if !d.After(after) {
+			return true
+		}
+		dates = append(dates, d)
+		return len(dates) < n
+	})
+	if len(dates) > 0 {
+		return dates, nil
+	}
+	if err == nil {
+		err = errNoDate
+	}
+	return nil, err
+}
+
+// Возвращает даты серии, начатой в start, из интервала [from, to], но не больше limit первых.
+// Перебор прекращается на limit-й дате, поэтому широкий интервал не перебирается целиком.
+func (r Rule) Between(start, from, to time.Time, limit int) []time.Time {
+	return r.between(start, from, to, limit, false)
+}
+
+// Возвращает не больше n последних дат серии, начатой в start, из интервала [from, to].
+func (r Rule) Last(start, from, to time.Time, n int) []time.Time {
+	return r.between(start, from, to, n, true)
+}
+
+// Возвращает даты серии из интервала [from, to]: не больше limit первых или, если last, последних.
+func (r Rule) between(start, from, to time.Time, limit int, last bool) []time.Time {
+	if r.Freq == FreqNone {
+		return nil
+	}
+
+	if r.intraday() {
+		return r.betweenIntraday(start, from, to, limit, last)
+	}
+
+	start, from, to = Day(start), Day(from), Day(to)
+	var res []time.Time
+	// Ошибка здесь означает лишь конец серии, а найденные до него даты уже собраны
+	_ = r.iterate(start, from, func(d time.Time) bool {
+		if d.After(to) {
+			return false
+		}
+		res = append(res, d)
+		if limit > 0 && len(res) > limit {
+			res = res[1:]
+		}
+		return limit == 0 || last || len(res) < limit
+	})
+	return res
+}
+
+// Вызывает fn для дат серии, начатой в start, в хронологическом порядке, начиная с from.
+// Перебор прекращается, когда fn возвращает false (результат nil),
+// когда серия закончилась (ErrSeriesEnded) или когда подходящих дат долго нет.
+func (r Rule) iterate(start, from time.Time, fn func(time.Time) bool) error {
+	limit := from.AddDate(maxGapYears+r.Interval, 0, 0)
+	n := 0
+	var last time.Time
+	// Учитывает очередную дату серии, возвращает false, если перебор нужно прекратить
+	visit := func(d time.Time) (bool, error) {
+		// Перенос на рабочий день может совместить соседние повторения
+		if d.Before(start) || !d.After(last) {
+			return true, nil
+		}
+		last = d
+		n++
+		if r.Count > 0 && n > r.Count {
+			return false, ErrSeriesEnded
+		}
+		if !r.Until.IsZero() && d.After(r.Until) {
+			return false, ErrSeriesEnded
+		}
+		if d.Before(from) || containsDay(r.Exclude, d) {
+			return true, nil
+		}
+		if !fn(d) {
+			return false, nil
+		}
+		limit = d.AddDate(maxGapYears+r.Interval, 0, 0)
+		return true, nil
+	}
+
+	// d N bd - каждый N-й рабочий день, поэтому дни перебираются подряд
+	if r.Freq == FreqDaily && r.Workday == WorkdayOnly {
+		cal := r.calendar()
+		i := 0
+		for d := start; !d.After(limit); d = d.AddDate(0, 0, 1) {
+			if !cal.IsWorkday(d) || !r.matchMonth(d.Month()) || !r.matchMonthDay(d) || !r.matchWeekday(d) {
+				continue
+			}
+			i++
+			if (i-1)%r.Interval != 0 {
+				continue
+			}
+			if ok, err := visit(d); !ok {
+				return err
+			}
+		}
+		return errNoDate
+	}
+
+	k := 0
+	// Без COUNT считать прошедшие повторения не нужно, поэтому сразу переходим к периоду с from
+	if r.Count == 0 {
+		k = r.periodIndex(start, from) - 1
+		if k < 0 {
+			k = 0
+		}
+	}
+
+	for ; !r.periodStart(start, k).After(limit); k++ {
+		for _, d := range r.expand(start, k) {
+			if ok, err := visit(d); !ok {
+				return err
+			}
+		}
+	}
+	return errNoDate
+}
+
+// Возвращает первый день k-го периода серии, начатой в start.
+func (r Rule) periodStart(start time.Time, k int) time.Time {
+	switch r.Freq {
+	case FreqDaily:
+		return start.AddDate(0, 0, k*r.Interval)
+	case FreqWeekly:
+		return r.weekStart(start).AddDate(0, 0, 7*k*r.Interval)
+	case FreqMonthly:
+		return time.Date(start.Year(), start.Month()+time.Month(k*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(start.Year()+k*r.Interval, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Возвращает номер периода серии, начатой в start, в который попадает день d.
+func (r Rule) periodIndex(start, d time.Time) int {
+	switch r.Freq {
+	case FreqDaily:
+		return daysBetween(start, d) / r.Interval
+	case FreqWeekly:
+		return daysBetween(r.weekStart(start), d) / (7 * r.Interval)
+	case FreqMonthly:
+		return ((d.Year()-start.Year())*12 + int(d.Month()) - int(start.Month())) / r.Interval
+	default:
+		return (d.Year() - start.Year()) / r.Interval
+	}
+}
+
+// Возвращает начало недели (с учетом WeekStart), в которую попадает d.
+func (r Rule) weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) - int(r.WeekStart) + 7) % 7))
+}
+
+// Возвращает отсортированные даты k-го периода серии с учетом BYxxx и BYSETPOS.
+func (r Rule) expand(start time.Time, k int) []time.Time {
+	period := r.periodStart(start, k)
+	var days []time.Time
+
+	switch r.Freq {
+	case FreqDaily:
+		if r.matchMonth(period.Month()) && r.matchMonthDay(period) && r.matchWeekday(period) {
+			days = append(days, period)
+		}
+
+	case FreqWeekly:
+		for i := 0; i < 7; i++ {
+			d := period.AddDate(0, 0, i)
+			if !r.matchMonth(d.Month()) {
+				continue
+			}
+			if len(r.ByDay) == 0 && d.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchWeekday(d) {
+				days = append(days, d)
+			}
+		}
+
+	case FreqMonthly:
+		if r.matchMonth(period.Month()) {
+			days = r.monthDays(start, period.Year(), period.Month())
+		}
+
+	case FreqYearly:
+		switch {
+		case len(r.ByMonthDate) > 0:
+			for _, md := range r.ByMonthDate {
+				day := min(md.Day, daysIn(period.Year(), md.Month))
+				days = append(days, time.Date(period.Year(), md.Month, day, 0, 0, 0, 0, time.UTC))
+			}
+		case len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+			// Простое ежегодное повторение: 29 февраля в невисокосный год переходит на 1 марта
+			days = append(days, start.AddDate(k*r.Interval, 0, 0))
+		case len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0:
+			days = expandByDay(r.ByDay, period, period.AddDate(1, 0, 0))
+		default:
+			months := r.ByMonth
+			if len(months) == 0 && len(r.ByMonthDay) > 0 {
+				for m := time.January; m <= time.December; m++ {
+					months = append(months, m)
+				}
+			}
+			for _, m := range months {
+				days = append(days, r.monthDays(start, period.Year(), m)...)
+			}
+		}
+	}
+
+	return r.applyWorkday(applySetPos(sortDays(days), r.BySetPos))
+}
+
+// Убирает нерабочие дни или переносит их на ближайший рабочий день.
+func (r Rule) applyWorkday(days []time.Time) []time.Time {
+	if r.Workday == WorkdayAny {
+		return days
+	}
+	cal := r.calendar()
+	res := make([]time.Time, 0, len(days))
+	for _, d := range days {
+		switch r.Workday {
+		case WorkdayOnly:
+			if !cal.IsWorkday(d) {
+				continue
+			}
+		case WorkdayNext:
+			d = cal.shift(d, 1)
+		case WorkdayPrev:
+			d = cal.shift(d, -1)
+		}
+		res = append(res, d)
+	}
+	return sortDays(res)
+}
+
+func (r Rule) calendar() *Calendar {
+	if r.Calendar != nil {
+		return r.Calendar
+	}
+	return defaultCalendar
+}
+
+// Возвращает дни месяца, подходящие под BYMONTHDAY и BYDAY.
+// Если ни то, ни другое не указано, берется день месяца из даты начала серии.
+func (r Rule) monthDays(start time.Time, year int, month time.Month) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	next := first.AddDate(0, 1, 0)
+	lastDay := next.AddDate(0, 0, -1).Day()
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if start.Day() > lastDay {
+			return nil
+		}
+		return []time.Time{first.AddDate(0, 0, start.Day()-1)}
+	}
+
+	if len(r.ByMonthDay) == 0 {
+		return expandByDay(r.ByDay, first, next)
+	}
+
+	var days []time.Time
+	for _, v := range r.ByMonthDay {
+		if v < 0 {
+			v = lastDay + 1 + v
+		}
+		if v < 1 || v > lastDay {
+			continue
+		}
+		d := first.AddDate(0, 0, v-1)
+		if len(r.ByDay) > 0 && !containsDay(expandByDay(r.ByDay, first, next), d) {
+			continue
+		}
+		days = append(days, d)
+	}
+	return days
+}
+
+func (r Rule) matchMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, v := range r.ByMonth {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	lastDay := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, v := range r.ByMonthDay {
+		if v < 0 {
+			v = lastDay + 1 + v
+		}
+		if d.Day() == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверяет день недели без учета порядковых номеров (для DAILY и WEEKLY).
+func (r Rule) matchWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Возвращает дни в интервале [from, to), подходящие под список BYDAY.
+// Порядковые номера отсчитываются от начала (или с конца) этого интервала.
+func expandByDay(byDay []WeekdayNum, from, to time.Time) []time.Time {
+	var days []time.Time
+	for _, wd := range byDay {
+		var all []time.Time
+		for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+			if d.Weekday() == wd.Weekday {
+				all = append(all, d)
+			}
+		}
+		switch {
+		case wd.N == 0:
+			days = append(days, all...)
+		case wd.N > 0 && wd.N <= len(all):
+			days = append(days, all[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(all):
+			days = append(days, all[len(all)+wd.N])
+		}
+	}
+	return sortDays(days)
+}
+
+// Оставляет из дат периода только позиции BYSETPOS.
+func applySetPos(days []time.Time, setPos []int) []time.Time {
+	if len(setPos) == 0 || len(days) == 0 {
+		return days
+	}
+	var res []time.Time
+	for _, p := range setPos {
+		switch {
+		case p > 0 && p <= len(days):
+			res = append(res, days[p-1])
+		case p < 0 && -p <= len(days):
+			res = append(res, days[len(days)+p])
+		}
+	}
+	return sortDays(res)
+}
+
+// Сортирует даты и убирает повторы.
+func sortDays(days []time.Time) []time.Time {
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+	res := days[:0]
+	for i, d := range days {
+		if i == 0 || !d.Equal(days[i-1]) {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func containsDay(days []time.Time, d time.Time) bool {
+	for _, v := range days {
+		if v.Equal(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Возвращает количество дней в месяце.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -2,423 +2,356 @@ package task
 
 import (
 	"fmt"
-	"sort"
-	"strconv"
-	"strings"
 	"time"
 )
 
 // Структура единицы репозитория - Task.
 type Task struct {
 	ID      string `json:"id"`
-	Date    string `json:"date"`
+	Date    Date   `json:"date"`
+	Time    string `json:"time,omitempty"` // время дня 15:04, пустое - задача на весь день
 	Title   string `json:"title,omitempty"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	Until   Date   `json:"until,omitzero"`    // дата, после которой серия повторений заканчивается
+	Count   int    `json:"count,omitempty"`   // после скольких выполнений серия заканчивается
+	Done    int    `json:"done,omitempty"`    // сколько раз задача уже выполнена
+	Exclude []Date `json:"exclude,omitempty"` // даты пропущенных повторений
+	Missed  string `json:"missed,omitempty"`  // политика пропущенных повторений: skip, step или overdue
+	// От чего отсчитывается следующая дата после выполнения: schedule (по умолчанию) или completion
+	Relative string `json:"relative,omitempty"`
+	// Описание правила повторения, заполняется обработчиками при выдаче задачи
+	Description string `json:"description,omitempty"`
+	// HTML-фрагмент заголовка или комментария с найденными словами в <mark>, заполняется при поиске
+	Snippet string `json:"snippet,omitempty"`
+	Start   Date   `json:"-"` // дата начала серии, от нее отсчитываются интервалы и COUNT
 }
 
 type TaskHandler interface {
-	GetNextRepeatDate() (string, error)
+	NextDate(now time.Time) (string, error)
 }
 
 const DateFormat = "20060102"
 
-// Возвращает новую дату для задачи в зависимости от значения, указанного в поле repeat.
-func (t *Task) GetNextRepeatDate() (string, error) {
-	if t.Date == "" {
-		t.Date = time.Now().Format(DateFormat)
-	}
-	switch {
-	case strings.HasPrefix(t.Repeat, "d "):
-		daysStr := strings.TrimPrefix(t.Repeat, "d ")
-		daysNum, err := strconv.Atoi(daysStr)
-		if err != nil {
-			return "", fmt.Errorf("неверный формат: %s ; %v", t.Repeat, err)
-		}
-
-		if daysNum >= 400 {
-			return "", fmt.Errorf("перенос задачи на 400 и более дней: %s;", t.Repeat)
-		}
-
-		taskDate, err := time.Parse(DateFormat, t.Date)
-
-		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
-		}
-
-		taskDate = taskDate.AddDate(0, 0, daysNum) // для учета пограничных вариантов
-
-		for taskDate.Before(time.Now()) {
-			taskDate = taskDate.AddDate(0, 0, daysNum)
-		}
+// Политики обработки повторений, пропущенных к моменту выполнения или добавления задачи.
+const (
+	MissedSkip    = "skip"    // сразу перейти к ближайшей будущей дате (по умолчанию)
+	MissedStep    = "step"    // перейти ровно на одно повторение, даже если оно уже в прошлом
+	MissedOverdue = "overdue" // перейти к будущей дате, а пропущенные повторения оставить просроченными задачами
+)
 
-		return taskDate.Format(DateFormat), nil
+// Режимы отсчета следующей даты повторяющейся задачи.
+const (
+	RelativeSchedule   = "schedule"   // от даты задачи по расписанию (по умолчанию)
+	RelativeCompletion = "completion" // от дня фактического выполнения
+)
 
-	case t.Repeat == "y":
-		taskDate, err := time.Parse(DateFormat, t.Date)
-		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
-		}
+// Сколько пропущенных повторений не больше сохраняется как просроченные задачи.
+const MaxOverdue = 100
 
-		taskDate = taskDate.AddDate(1, 0, 0) // для учета пограничных вариантов
-		for taskDate.Before(time.Now()) {
-			taskDate = taskDate.AddDate(1, 0, 0)
-		}
-		return taskDate.Format(DateFormat), nil
-
-	case strings.HasPrefix(t.Repeat, "w "):
-		weekdaysStr := strings.Split(strings.TrimPrefix(t.Repeat, "w "), ",")
-		weekdaysInt := make([]int, len(weekdaysStr))
-		for i := range weekdaysStr {
-			num, err := strconv.Atoi(weekdaysStr[i])
-			if err != nil {
-				return "", fmt.Errorf("неверный формат: %s ; %v", t.Repeat, err)
-			}
-			if num > 7 || num < 0 {
-				return "", fmt.Errorf("неверный формат: %s ; %v", t.Repeat, err)
-			}
-			if num == 7 {
-				num = 0
-			}
-			weekdaysInt[i] = num
-		}
-		taskDate, err := time.Parse(DateFormat, t.Date)
-		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
-		}
+// Возвращает разобранное правило повторения задачи с учетом даты окончания серии.
+func (t Task) Rule() (Rule, error) {
+	rule, err := ParseRule(t.Repeat)
+	if err != nil {
+		return rule, err
+	}
 
-		if !taskDate.After(time.Now()) {
-			taskDate = time.Now().AddDate(0, 0, 1)
-		}
+	if t.Count < 0 {
+		return Rule{}, fmt.Errorf("неверное количество повторений: %d", t.Count)
+	}
+	if rule.Freq == FreqNone && (!t.Until.IsZero() || t.Count > 0) {
+		return Rule{}, fmt.Errorf("окончание серии указывается только для повторяющихся задач")
+	}
 
-		found := false
-		for {
-			for _, day := range weekdaysInt {
+	switch t.Missed {
+	case "", MissedSkip, MissedStep, MissedOverdue:
+	default:
+		return Rule{}, fmt.Errorf("неверная политика пропущенных повторений: %s", t.Missed)
+	}
+	if rule.Freq == FreqNone && t.Missed != "" {
+		return Rule{}, fmt.Errorf("политика пропущенных повторений указывается только для повторяющихся задач")
+	}
+	if rule.intraday() && t.Missed == MissedOverdue {
+		return Rule{}, fmt.Errorf("политика overdue не используется с повторениями внутри дня")
+	}
+	if _, err := parseClock(t.Time); err != nil {
+		return Rule{}, err
+	}
 
-				if int(taskDate.Weekday()) == day {
-					found = true
-					break
-				}
+	switch t.Relative {
+	case "", RelativeSchedule, RelativeCompletion:
+	default:
+		return Rule{}, fmt.Errorf("неверный режим отсчета повторений: %s", t.Relative)
+	}
+	if rule.Freq == FreqNone && t.Relative != "" {
+		return Rule{}, fmt.Errorf("режим отсчета указывается только для повторяющихся задач")
+	}
 
-			}
-			if found {
-				break
-			}
-			taskDate = taskDate.AddDate(0, 0, 1)
+	for _, v := range t.Exclude {
+		if v.IsZero() {
+			return Rule{}, fmt.Errorf("%w: исключенная дата не указана", ErrInvalidDate)
 		}
-		return taskDate.Format(DateFormat), nil
+		rule.Exclude = append(rule.Exclude, v.Time())
+	}
+	if rule.Freq == FreqNone && len(rule.Exclude) > 0 {
+		return Rule{}, fmt.Errorf("исключенные даты указываются только для повторяющихся задач")
+	}
 
-	case strings.HasPrefix(t.Repeat, "m "):
-		splitted := strings.Split(t.Repeat, " ")
-		fmt.Println(splitted)
-		if len(splitted) > 3 || len(splitted) < 2 {
-			return "", fmt.Errorf("неверный формат: %s;", t.Repeat)
+	if !t.Until.IsZero() {
+		if rule.Until.IsZero() || t.Until.Time().Before(rule.Until) {
+			rule.Until = t.Until.Time()
 		}
+	}
+	return rule, nil
+}
 
-		daysStr := strings.Split(splitted[1], ",")
-		var daysNum []int
-
-		for i := range daysStr {
-			dayNum, err := strconv.Atoi(daysStr[i])
-			if err != nil || dayNum > 31 || dayNum == 0 || dayNum < -2 {
-				return "", fmt.Errorf("неверный формат: %s;", t.Repeat)
-			}
-
-			daysNum = append(daysNum, dayNum)
-		}
+// Сообщает, выполнена ли задача заданное в Count количество раз.
+func (t Task) Finished() bool {
+	return t.Count > 0 && t.Done >= t.Count
+}
 
-		sort.Slice(daysNum, func(i, j int) bool {
-			return daysNum[i] < daysNum[j]
-		})
+// Возвращает правило повторения задачи вместе с началом серии и текущей датой задачи.
+// Если у задачи указано время, оно добавляется к обеим датам.
+func (t Task) series() (rule Rule, start, date time.Time, err error) {
+	rule, err = t.Rule()
+	if err != nil {
+		return
+	}
+	clock, _ := parseClock(t.Time)
 
-		taskDate, err := time.Parse(DateFormat, t.Date)
-		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
-		}
+	if t.Date.IsZero() {
+		err = fmt.Errorf("%w: дата задачи не указана", ErrInvalidDate)
+		return
+	}
+	date = t.Date.Time().Add(clock)
 
-		if !taskDate.After(time.Now()) {
-			taskDate = time.Now().AddDate(0, 0, 1)
-		}
-		found := false
+	// Повторения внутри дня отсчитываются от текущего повторения: оно всегда лежит на сетке интервала
+	start = date
+	if !t.Start.IsZero() && !rule.intraday() {
+		start = t.Start.Time().Add(clock)
+	}
+	return
+}
 
-		taskDate = checkFirstMonth(daysNum, taskDate)
+// Возвращает следующее повторение задачи с учетом ее времени.
+// Для неповторяющейся задачи возвращает нулевое время.
+// Если серия повторений закончилась (по Until или COUNT в RRULE), возвращает ErrSeriesEnded.
+func (t Task) NextAt(now time.Time) (time.Time, error) {
+	rule, start, taskDate, err := t.series()
+	if err != nil || rule.Freq == FreqNone {
+		return time.Time{}, err
+	}
 
-		if len(splitted) == 3 {
+	// Следующее повторение должно быть позже текущей даты задачи
+	rule.Location = now.Location()
+	if taskDate.After(Wall(now)) {
+		now = taskDate
+	}
 
-			monthsStr := strings.Split(splitted[2], ",")
-			var months []int
+	next, err := rule.Next(start, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.withClock(rule, next), nil
+}
 
-			for i := range monthsStr {
-				mthNum, err := strconv.Atoi(monthsStr[i])
-				if err != nil || mthNum > 12 || mthNum < 1 {
-					return "", fmt.Errorf("неверный формат: %s;", t.Repeat)
-				}
+// Возвращает новую дату для задачи в зависимости от значения, указанного в поле repeat.
+// Для неповторяющейся задачи возвращает пустую строку.
+// Если серия повторений закончилась (по Until или COUNT в RRULE), возвращает ErrSeriesEnded.
+func (t Task) NextDate(now time.Time) (string, error) {
+	next, err := t.NextAt(now)
+	if err != nil || next.IsZero() {
+		return "", err
+	}
+	return next.Format(DateFormat), nil
+}
 
-				months = append(months, mthNum)
-			}
+// Возвращает следующее повторение задачи строкой: только дату или дату со временем,
+// если у задачи есть время или она повторяется внутри дня.
+func (t Task) NextDateTime(now time.Time) (string, error) {
+	next, err := t.NextAt(now)
+	if err != nil || next.IsZero() {
+		return "", err
+	}
+	rule, _ := t.Rule()
+	return next.Format(t.layout(rule)), nil
+}
 
-			sort.Slice(months, func(i, j int) bool {
-				return months[i] < months[j]
-			})
+// Возвращает до n ближайших дат, на которые задача будет переноситься при выполнении.
+// Для задач со временем и правил h N, min N даты возвращаются вместе со временем.
+func (t Task) Upcoming(now time.Time, n int) ([]string, error) {
+	rule, start, taskDate, err := t.series()
+	if err != nil || rule.Freq == FreqNone {
+		return nil, err
+	}
 
-			for {
-				for _, v := range months {
-					if int(taskDate.Month()) == v {
-						found = true
-						break
-					}
-				}
+	rule.Location = now.Location()
+	if taskDate.After(Wall(now)) {
+		now = taskDate
+	}
 
-				if found {
-					break
-				}
+	// Текущая дата задачи - повторение номер Done+1, после него остается Count-Done-1
+	if t.Count > 0 && n > t.Count-t.Done-1 {
+		n = t.Count - t.Done - 1
+		if n <= 0 {
+			return nil, ErrSeriesEnded
+		}
+	}
 
-				taskDate = taskDate.AddDate(0, 1, 0)
-			}
+	dates, err := rule.NextN(start, now, n)
+	if err != nil {
+		return nil, err
+	}
+	return t.formatDates(rule, dates), nil
+}
 
-		}
+// Возвращает не больше limit первых дат повторений задачи из интервала [from, to],
+// начиная с текущей даты задачи. Без limit возвращаются все даты интервала.
+func (t Task) Between(from, to time.Time, limit int) ([]string, error) {
+	rule, start, taskDate, err := t.series()
+	if err != nil || rule.Freq == FreqNone {
+		return nil, err
+	}
 
-		found = false
-		for {
-			for _, v := range daysNum {
-				if v < 0 {
-					v = v + 1
-					lastDay := time.Date(taskDate.Year(), taskDate.Month()+1, 0, 0, 0, 0, 0, time.UTC)
-					v = lastDay.AddDate(0, 0, v).Day()
-				}
-
-				if taskDate.Day() == v {
-					found = true
-					break
-				}
-			}
-
-			if found {
-				break
-			}
-
-			taskDate = taskDate.AddDate(0, 0, 1)
-		}
-		return taskDate.Format(DateFormat), nil
-	case t.Repeat == "":
-		return "", nil
-	default:
-		return "", fmt.Errorf("неверный формат поля 't.Repeat': %s", t.Repeat)
+	rule.Location = from.Location()
+	if taskDate.After(from) {
+		from = taskDate
 	}
+	return t.formatDates(rule, rule.Between(start, from, to, limit)), nil
 }
 
-// Вспомогательная функция, проверяющая есть ли в месяце дни, соответствующие указателю repeat.
-func checkFirstMonth(daysNum []int, taskDate time.Time) time.Time {
-	startMonth := taskDate.Month()
-	found := false
-	for {
-		for _, v := range daysNum {
-			if v < 0 {
-				v = v + 1
-				lastDay := time.Date(taskDate.Year(), taskDate.Month()+1, 0, 0, 0, 0, 0, time.UTC)
-				v = lastDay.AddDate(0, 0, v).Day()
-			}
-
-			if taskDate.Day() == v {
-				found = true
-				break
-			}
-		}
+// Переносит задачу на момент d: меняет дату, а для задач со временем и правил h N, min N - и время.
+func (t *Task) MoveTo(d time.Time) {
+	t.Date = DateOf(d)
+	if t.Time != "" || d.Hour() != 0 || d.Minute() != 0 {
+		t.Time = d.Format(TimeFormat)
+	}
+}
 
-		if found {
-			break
-		}
+// Добавляет к дню d время задачи. Повторения внутри дня уже содержат время.
+func (t Task) withClock(rule Rule, d time.Time) time.Time {
+	if rule.intraday() {
+		return d
+	}
+	clock, _ := parseClock(t.Time)
+	return Day(d).Add(clock)
+}
 
-		taskDate = taskDate.AddDate(0, 0, 1)
-		if taskDate.Month() != startMonth {
-			return taskDate
-		}
+// Возвращает формат дат задачи: со временем, если у задачи есть время или она повторяется внутри дня.
+func (t Task) layout(rule Rule) string {
+	if t.Time != "" || rule.intraday() {
+		return DateTimeFormat
 	}
-	return taskDate
+	return DateFormat
 }
 
-// Возвращает новую дату для задачи в зависимости от значения, указанного в поле repeat.
-// Сделана для прохождения тестов. Логика не отличается от используемой в программе функции.
-func (t *Task) GetNextRepeatDateTest(now string) (string, error) {
-	nowTime, err := time.Parse(DateFormat, now)
+// Возвращает описание правила повторения задачи на языке lang с учетом условий окончания серии.
+func (t Task) Describe(lang string) (string, error) {
+	rule, err := t.Rule()
 	if err != nil {
 		return "", err
 	}
-	switch {
-	case strings.HasPrefix(t.Repeat, "d "):
-		daysStr := strings.TrimPrefix(t.Repeat, "d ")
-		daysNum, err := strconv.Atoi(daysStr)
-		if err != nil {
-			return "", fmt.Errorf("неверный формат: %s ; %v", t.Repeat, err)
-		}
-
-		if daysNum >= 400 {
-			return "", fmt.Errorf("перенос задачи на 400 и более дней: %s;", t.Repeat)
-		}
-
-		taskDate, err := time.Parse(DateFormat, t.Date)
 
-		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
+	desc := rule.Describe(lang)
+	if t.Count > 0 {
+		desc += " " + timesText(t.Count, lang)
+	}
+	if t.Relative == RelativeCompletion {
+		if lang == LangEn {
+			desc += " after completion"
+		} else {
+			desc += " от даты выполнения"
 		}
+	}
+	return desc, nil
+}
 
-		taskDate = taskDate.AddDate(0, 0, daysNum) // для учета пограничных вариантов
-
-		for taskDate.Before(nowTime) {
-			taskDate = taskDate.AddDate(0, 0, daysNum)
-		}
+// Возвращает даты повторений после текущей даты задачи и не позже дня now (не больше MaxOverdue последних).
+func (t Task) MissedDates(now time.Time) ([]string, error) {
+	rule, start, taskDate, err := t.series()
+	if err != nil || rule.Freq == FreqNone {
+		return nil, err
+	}
 
-		return taskDate.Format(DateFormat), nil
+	rule.Location = now.Location()
+	return formatDates(rule.Last(start, Day(taskDate).AddDate(0, 0, 1), now, MaxOverdue)), nil
+}
 
-	case t.Repeat == "y":
-		taskDate, err := time.Parse(DateFormat, t.Date)
-		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
-		}
+// Возвращает момент, на который переносится задача после выполнения, с учетом политики Missed,
+// и даты пропущенных повторений, которые по политике MissedOverdue становятся просроченными задачами.
+// В режиме RelativeCompletion серия начинается заново от момента выполнения now, и пропущенных повторений нет.
+func (t Task) Advance(now time.Time) (time.Time, []string, error) {
+	if t.Relative == RelativeCompletion {
+		t.Start = DateOf(now)
+		t.Date = t.Start
+		if rule, err := t.Rule(); err == nil && rule.intraday() {
+			t.Time = now.Format(TimeFormat)
+		}
+		next, err := t.NextAt(now)
+		return next, nil, err
+	}
 
-		taskDate = taskDate.AddDate(1, 0, 0) // для учета пограничных вариантов
-		for taskDate.Before(nowTime) {
-			taskDate = taskDate.AddDate(1, 0, 0)
-		}
-		return taskDate.Format(DateFormat), nil
-
-	case strings.HasPrefix(t.Repeat, "w "):
-		weekdaysStr := strings.Split(strings.TrimPrefix(t.Repeat, "w "), ",")
-		weekdaysInt := make([]int, len(weekdaysStr))
-		for i := range weekdaysStr {
-			num, err := strconv.Atoi(weekdaysStr[i])
-			if err != nil {
-				return "", fmt.Errorf("неверный формат: %s ; %v", t.Repeat, err)
-			}
-			if num > 7 || num < 0 {
-				return "", fmt.Errorf("неверный формат: %s ; %v", t.Repeat, err)
-			}
-			if num == 7 {
-				num = 0
-			}
-			weekdaysInt[i] = num
-		}
-		taskDate, err := time.Parse(DateFormat, t.Date)
+	switch t.Missed {
+	case MissedStep:
+		// Время "сейчас" не учитывается: берется первое повторение после текущей даты задачи.
+		// От now остается только часовой пояс
+		next, err := t.NextAt(time.Time{}.In(now.Location()))
+		return next, nil, err
+	case MissedOverdue:
+		missed, err := t.MissedDates(now)
 		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
-		}
-
-		if !taskDate.After(nowTime) {
-			taskDate = nowTime.AddDate(0, 0, 1)
-		}
-
-		found := false
-		for {
-			for _, day := range weekdaysInt {
-
-				if int(taskDate.Weekday()) == day {
-					found = true
-					break
-				}
-
-			}
-			if found {
-				break
-			}
-			taskDate = taskDate.AddDate(0, 0, 1)
-		}
-		return taskDate.Format(DateFormat), nil
-
-	case strings.HasPrefix(t.Repeat, "m "):
-		splitted := strings.Split(t.Repeat, " ")
-		fmt.Println(splitted)
-		if len(splitted) > 3 || len(splitted) < 2 {
-			return "", fmt.Errorf("неверный формат: %s;", t.Repeat)
-		}
-
-		daysStr := strings.Split(splitted[1], ",")
-		var daysNum []int
-
-		for i := range daysStr {
-			dayNum, err := strconv.Atoi(daysStr[i])
-			if err != nil || dayNum > 31 || dayNum == 0 || dayNum < -2 {
-				return "", fmt.Errorf("неверный формат: %s;", t.Repeat)
-			}
-
-			daysNum = append(daysNum, dayNum)
+			return time.Time{}, nil, err
 		}
+		next, err := t.NextAt(now)
+		return next, missed, err
+	default:
+		next, err := t.NextAt(now)
+		return next, nil, err
+	}
+}
 
-		sort.Slice(daysNum, func(i, j int) bool {
-			return daysNum[i] < daysNum[j]
-		})
+// Возвращает момент для новой задачи, дата которой раньше дня now, с учетом политики Missed:
+// неповторяющаяся задача ставится на сегодня, с MissedStep дата не меняется,
+// иначе задача переносится на ближайшее будущее повторение. Для MissedOverdue также
+// возвращаются исходная дата и пропущенные повторения, которые становятся просроченными задачами.
+func (t Task) Reschedule(now time.Time) (time.Time, []string, error) {
+	rule, _, taskDate, err := t.series()
+	if err != nil {
+		return time.Time{}, nil, err
+	}
 
-		taskDate, err := time.Parse(DateFormat, t.Date)
+	switch {
+	case rule.Freq == FreqNone:
+		return t.withClock(rule, now), nil, nil
+	case t.Missed == MissedStep:
+		return taskDate, nil, nil
+	case t.Missed == MissedOverdue:
+		missed, err := t.MissedDates(now)
 		if err != nil {
-			return "", fmt.Errorf("ошибка при считывании даты: %s", t.Date)
-		}
-
-		if !taskDate.After(nowTime) {
-			taskDate = nowTime.AddDate(0, 0, 1)
+			return time.Time{}, nil, err
 		}
-		found := false
-
-		taskDate = checkFirstMonth(daysNum, taskDate)
-
-		if len(splitted) == 3 {
-
-			monthsStr := strings.Split(splitted[2], ",")
-			var months []int
-
-			for i := range monthsStr {
-				mthNum, err := strconv.Atoi(monthsStr[i])
-				if err != nil || mthNum > 12 || mthNum < 1 {
-					return "", fmt.Errorf("неверный формат: %s;", t.Repeat)
-				}
-
-				months = append(months, mthNum)
-			}
-
-			sort.Slice(months, func(i, j int) bool {
-				return months[i] < months[j]
-			})
-
-			for {
-				for _, v := range months {
-					if int(taskDate.Month()) == v {
-						found = true
-						break
-					}
-				}
-
-				if found {
-					break
-				}
+		next, err := t.NextAt(now)
+		return next, append([]string{t.Date.String()}, missed...), err
+	default:
+		next, err := t.NextAt(now)
+		return next, nil, err
+	}
+}
 
-				taskDate = taskDate.AddDate(0, 1, 0)
-			}
-			//
-		}
+// Возвращает даты повторений задачи в формате layout(rule).
+func (t Task) formatDates(rule Rule, dates []time.Time) []string {
+	layout := t.layout(rule)
+	res := make([]string, len(dates))
+	for i, d := range dates {
+		res[i] = t.withClock(rule, d).Format(layout)
+	}
+	return res
+}
 
-		found = false
-		for {
-			for _, v := range daysNum {
-				if v < 0 {
-					v = v + 1
-					lastDay := time.Date(taskDate.Year(), taskDate.Month()+1, 0, 0, 0, 0, 0, time.UTC)
-					v = lastDay.AddDate(0, 0, v).Day()
-				}
-
-				if taskDate.Day() == v {
-					found = true
-					break
-				}
-			}
-
-			if found {
-				break
-			}
-
-			taskDate = taskDate.AddDate(0, 0, 1)
-		}
-		return taskDate.Format(DateFormat), nil
-	case t.Repeat == "":
-		return "", nil
-	default:
-		return "", fmt.Errorf("неверный формат поля 't.Repeat': %s", t.Repeat)
+func formatDates(dates []time.Time) []string {
+	res := make([]string, len(dates))
+	for i, d := range dates {
+		res[i] = d.Format(DateFormat)
 	}
+	return res
 }
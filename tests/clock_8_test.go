@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/handlers"
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Часы в прошлом: если где-то используется time.Now(), даты уедут в текущий год.
+func TestInjectedClock(t *testing.T) {
+	clock := todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+	date := func(year int, month time.Month, day int) todo.Date {
+		return todo.DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+	}
+
+	next, err := todo.Task{Date: date(2024, 1, 1), Repeat: "d 7"}.NextDate(clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "20240129", next)
+	next, err = todo.Task{Date: date(2023, 1, 1), Repeat: "y"}.NextDate(clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "20250101", next)
+
+	t.Setenv("TODO_DFILE", filepath.Join(t.TempDir(), "clock.db"))
+	repo, err := repository.NewRepo()
+	require.NoError(t, err)
+	defer repo.Store.Close()
+	repo.Clock = clock
+
+	// Прошедшая разовая задача ставится на сегодня по часам репозитория
+	id, err := repo.AddTask(todo.Task{Date: date(2024, 1, 20), Title: "Разовая"})
+	require.NoError(t, err)
+	task, err := repo.GetTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, "20240126", task.Date.String())
+
+	// Прошедшая повторяющаяся задача переносится на ближайшее повторение после сегодня
+	id, err = repo.AddTask(todo.Task{Date: date(2024, 1, 1), Title: "Еженедельная", Repeat: "d 7"})
+	require.NoError(t, err)
+	task, err = repo.GetTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, "20240129", task.Date.String())
+
+	// Выполнение переносит задачу от сегодняшнего дня по часам репозитория
+	id, err = repo.AddTask(todo.Task{Date: date(2024, 1, 26), Title: "Через день", Repeat: "d 2"})
+	require.NoError(t, err)
+	require.NoError(t, repo.DoneTask(id))
+	task, err = repo.GetTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, "20240128", task.Date.String())
+
+	// Без параметра now /api/nextdate считает от часов обработчика
+	h := handlers.Handler{RP: repo, Clock: clock}
+	w := httptest.NewRecorder()
+	h.HandleDate(w, httptest.NewRequest(http.MethodGet, "/api/nextdate?date=20240101&repeat=d+7", nil))
+	assert.Equal(t, "20240129", w.Body.String())
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestISODate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().AddDate(0, 0, 2)
+	id := addTaskJSON(t, map[string]any{
+		"date":  now.Format(`2006-01-02`),
+		"title": "Дата в формате ISO",
+	})
+	assert.Equal(t, now.Format(`20060102`), getTask(t, id)["date"])
+
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  "2024-02-30",
+		"title": "Несуществующая дата",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  now.AddDate(0, 0, 1).Format(`2006-01-02`),
+		"title": "Дата в формате ISO",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), getTask(t, id)["date"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	for _, date := range []string{"2024-1-5", "26.01.2024", "20241301"} {
+		ret, err = postJSON("api/task", map[string]any{
+			"date":  date,
+			"title": "Неверная дата",
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], date)
+	}
+
+	// Исключенная дата возвращается в серию в любом из форматов
+	day := time.Now().AddDate(0, 0, 1)
+	id = addTaskJSON(t, map[string]any{
+		"date":   day.Format(`20060102`),
+		"title":  "Исключение в формате ISO",
+		"repeat": "d 1",
+	})
+	for _, v := range []struct{ exclude, include string }{
+		{`2006-01-02`, `2006-01-02`},
+		{`2006-01-02`, `20060102`},
+		{`20060102`, `2006-01-02`},
+	} {
+		d := day.AddDate(0, 0, 2)
+		ret, err = postJSON("api/task/exdate?id="+id+"&date="+d.Format(v.exclude), nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+		assert.Equal(t, []any{d.Format(`20060102`)}, getTask(t, id)["exclude"])
+
+		ret, err = postJSON("api/task/exdate?id="+id+"&date="+d.Format(v.include), nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret, v)
+		assert.Nil(t, getTask(t, id)["exclude"], v)
+	}
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	get, err := getBody("api/nextdate?now=20240126&date=2024-01-20&repeat=d%207")
+	assert.NoError(t, err)
+	assert.Equal(t, "20240127", strings.TrimSpace(string(get)))
+}
+
+// Даты окончания серии, исключенные даты, UNTIL в RRULE и интервал предпросмотра
+// принимаются в формате ISO 8601 так же, как дата задачи.
+func TestISODateFields(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	id := addTaskJSON(t, map[string]any{
+		"date":    now.Format(`2006-01-02`),
+		"title":   "Поля в формате ISO",
+		"repeat":  "d 1",
+		"until":   now.AddDate(0, 0, 5).Format(`2006-01-02`),
+		"exclude": []string{now.AddDate(0, 0, 2).Format(`2006-01-02`), now.AddDate(0, 0, 1).Format(`20060102`)},
+	})
+	m := getTask(t, id)
+	assert.Equal(t, now.AddDate(0, 0, 5).Format(`20060102`), m["until"])
+	assert.Equal(t, []any{now.AddDate(0, 0, 1).Format(`20060102`), now.AddDate(0, 0, 2).Format(`20060102`)}, m["exclude"])
+
+	doneTask(t, id)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), getTask(t, id)["date"])
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	for _, v := range []map[string]any{
+		{"title": "Неверное окончание", "repeat": "d 1", "until": "2030-02-30"},
+		{"title": "Неверное исключение", "repeat": "d 1", "exclude": []string{"2030-1-5"}},
+	} {
+		ret, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v)
+	}
+
+	s := getSeries(t, url.Values{
+		"now": {"20240126"}, "date": {"2024-01-01"}, "repeat": {"RRULE:FREQ=DAILY;UNTIL=2024-01-28"},
+	})
+	assert.Empty(t, s.Error)
+	assert.Equal(t, []string{"20240127", "20240128"}, s.Dates)
+
+	s = getSeries(t, url.Values{
+		"now": {"20240126"}, "date": {"20240126"}, "repeat": {"d 1"}, "until": {"2024-01-28"},
+	})
+	assert.Empty(t, s.Error)
+	assert.Equal(t, []string{"20240127", "20240128"}, s.Dates)
+
+	s = getSeries(t, url.Values{
+		"date": {"20240101"}, "repeat": {"w 1,5 /2"}, "from": {"2024-01-01"}, "to": {"2024-01-15"},
+	})
+	assert.Empty(t, s.Error)
+	assert.Equal(t, []string{"20240101", "20240105", "20240115"}, s.Dates)
+
+	s = getSeries(t, url.Values{"date": {"20240101"}, "repeat": {"d 1"}, "from": {"2024-01-15"}, "to": {"2024-01-01"}})
+	assert.NotEmpty(t, s.Error)
+}
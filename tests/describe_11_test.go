@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRepeat(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		repeat string
+		want   string
+		desc   string
+	}{
+		{"m 31,-1,1", "m 1,31,-1", "каждый месяц 1-го, 31-го и последнего числа"},
+		{"w 7,1,1", "w 0,1", "каждую неделю по воскресеньям и понедельникам"},
+		{"m -1:5,2:2 12,1", "m 2:2,-1:5 1,12", "каждый месяц во второй вторник и в последнюю пятницу в январе и декабре"},
+		{"rrule:freq=weekly;byday=fr,mo;interval=2", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "раз в 2 недели по понедельникам и пятницам"},
+	}
+	for _, v := range tbl {
+		id := addTaskJSON(t, map[string]any{
+			"date":   time.Now().Format(`20060102`),
+			"title":  "Нормализация",
+			"repeat": v.repeat,
+		})
+		m := getTask(t, id)
+		assert.Equal(t, v.want, m["repeat"])
+		assert.Equal(t, v.desc, m["description"])
+
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
+
+func TestDescribeEn(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		repeat string
+		desc   string
+	}{
+		{"d 3", "every 3 days"},
+		{"w 3,1", "every week on Monday and Wednesday"},
+		{"m 31,-1,1", "every month on the 1st, 31st and last day"},
+		{"m 2:2,-1:5", "every month on the second Tuesday and the last Friday"},
+		{"RRULE:FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25;COUNT=3", "every year on December 25 3 times"},
+	}
+	for _, v := range tbl {
+		s := getSeries(t, url.Values{"date": {"20240101"}, "repeat": {v.repeat}, "lang": {"en"}})
+		assert.Empty(t, s.Error)
+		assert.Equal(t, v.desc, s.Description)
+	}
+}
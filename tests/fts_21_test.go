@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Возвращает заголовки найденных задач в порядке выдачи.
+func searchTitles(t *testing.T, repo *repository.Repository, search string) []string {
+	tasks, err := repo.SearchTask(search)
+	require.NoError(t, err)
+	titles := []string{}
+	for _, v := range tasks {
+		titles = append(titles, v.Title)
+	}
+	return titles
+}
+
+func TestFullTextSearch(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "fts.db"),
+		"memory": "",
+	}
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, err := repository.Open(name, dsn)
+			require.NoError(t, err)
+			defer repo.Store.Close()
+			repo.Clock = todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+
+			for _, v := range []todo.Task{
+				{Date: mustDate(t, "20240201"), Title: "Проверить баланс", Comment: "по карте <Visa>"},
+				{Date: mustDate(t, "20240127"), Title: "Купить молоко", Comment: "и хлеб"},
+				{Date: mustDate(t, "20240128"), Title: "Магазин", Comment: "купить молоко, баланс не забыть"},
+				{Date: mustDate(t, "20240129"), Title: "Молоко купить", Comment: ""},
+			} {
+				_, err := repo.AddTask(v)
+				require.NoError(t, err)
+			}
+
+			// Регистр не учитывается и для кириллицы, совпадения в заголовке выше
+			assert.Equal(t, []string{"Проверить баланс", "Магазин"}, searchTitles(t, repo, "БАЛАНС"))
+			assert.Equal(t, []string{"Проверить баланс", "Магазин"}, searchTitles(t, repo, "балан*"))
+			assert.Equal(t, []string{"Проверить баланс", "Магазин"}, searchTitles(t, repo, "балан"))
+
+			// Все слова запроса, фраза - целиком
+			titles := searchTitles(t, repo, "молоко купить")
+			require.Len(t, titles, 3)
+			assert.ElementsMatch(t, []string{"Купить молоко", "Молоко купить"}, titles[:2])
+			assert.Equal(t, "Магазин", titles[2])
+			assert.Equal(t, []string{"Купить молоко", "Магазин"}, searchTitles(t, repo, `"купить молоко"`))
+			assert.Equal(t, []string{"Магазин"}, searchTitles(t, repo, "молоко баланс"))
+			assert.Empty(t, searchTitles(t, repo, "молоко сыр"))
+
+			// Синтаксис FTS5 и спецсимволы в запросе не вызывают ошибок
+			assert.Empty(t, searchTitles(t, repo, `^ + - { } * : NEAR`))
+			assert.Equal(t, []string{"Проверить баланс"}, searchTitles(t, repo, "visa"))
+
+			tasks, err := repo.SearchTask("visa")
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, "по карте &lt;<mark>Visa</mark>&gt;", tasks[0].Snippet)
+			tasks, err = repo.SearchTask("хлеб")
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, "и <mark>хлеб</mark>", tasks[0].Snippet)
+
+			// Индекс следует за изменениями задач
+			got, err := repo.GetTask(tasks[0].ID)
+			require.NoError(t, err)
+			got.Comment = "и сыр"
+			require.NoError(t, repo.UpdateTask(got))
+			assert.Empty(t, searchTitles(t, repo, "хлеб"))
+			assert.Equal(t, []string{"Купить молоко"}, searchTitles(t, repo, "сыр"))
+			require.NoError(t, repo.DeleteTask(got.ID))
+			assert.Empty(t, searchTitles(t, repo, "сыр"))
+		})
+	}
+}
+
+func TestFullTextBackfill(t *testing.T) {
+	t.Parallel()
+
+	dbFile := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite", dbFile)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE scheduler (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT,  title TEXT, comment TEXT, repeat TEXT)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240126', 'Старая задача', 'Оплатить Интернет', '')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	repo, err := repository.Open("sqlite", dbFile)
+	require.NoError(t, err)
+	defer repo.Store.Close()
+	assert.Equal(t, []string{"Старая задача"}, searchTitles(t, repo, "интернет"))
+}
+
+func TestSearchSnippetAPI(t *testing.T) {
+	t.Parallel()
+
+	repo, err := repository.Open("sqlite", filepath.Join(t.TempDir(), "api.db"))
+	require.NoError(t, err)
+	defer repo.Store.Close()
+	ts := startServer(repo, todo.SystemClock)
+	defer ts.Close()
+
+	_, err = repo.AddTask(todo.Task{Title: "Позвонить в банк", Comment: "уточнить баланс"})
+	require.NoError(t, err)
+
+	var m map[string][]map[string]string
+	body := serverRequest(t, ts, http.MethodGet, "api/tasks?search="+url.QueryEscape("Баланс"), nil)
+	require.NoError(t, json.Unmarshal(body, &m))
+	require.Len(t, m["tasks"], 1)
+	assert.Equal(t, "уточнить <mark>баланс</mark>", m["tasks"][0]["snippet"])
+}
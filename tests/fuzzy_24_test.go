@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzySearch(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "fuzzy.db"),
+		"memory": "",
+	}
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, err := repository.Open(name, dsn)
+			require.NoError(t, err)
+			defer repo.Store.Close()
+			repo.Clock = todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+
+			for _, v := range []todo.Task{
+				{Date: mustDate(t, "20240126"), Title: "Проверить баланс", Comment: "по карте"},
+				{Date: mustDate(t, "20240127"), Title: "Купить молоко", Comment: "и хлеб"},
+				{Date: mustDate(t, "20240128"), Title: "Ёлка", Comment: "нарядить"},
+				{Date: mustDate(t, "20240129"), Title: "ΣΟΦΙΑ birthday", Comment: ""},
+				{Date: mustDate(t, "20240130"), Title: "Пилить дрова", Comment: ""},
+				{Date: mustDate(t, "20240131"), Title: "Палить костер", Comment: ""},
+				{Date: mustDate(t, "20240201"), Title: "Полить цветы", Comment: ""},
+			} {
+				_, err := repo.AddTask(v)
+				require.NoError(t, err)
+			}
+
+			tbl := []struct {
+				query  string
+				titles []string
+			}{
+				// Регистр и "ё" не учитываются для всех алфавитов
+				{"Баланс", []string{"Проверить баланс"}},
+				{"БАЛАНС", []string{"Проверить баланс"}},
+				{"елка", []string{"Ёлка"}},
+				{"σοφια", []string{"ΣΟΦΙΑ birthday"}},
+				// и в словах короче трех букв, для которых нет троек букв
+				{"ел", []string{"Ёлка"}},
+				{"ЕЛ НА", []string{"Ёлка"}},
+				{"σο", []string{"ΣΟΦΙΑ birthday"}},
+				// Опечатки: замена, перестановка, лишняя и пропущенная буква
+				{"баланц", []string{"Проверить баланс"}},
+				{"блаанс", []string{"Проверить баланс"}},
+				{"малоко", []string{"Купить молоко"}},
+				{"моолоко", []string{"Купить молоко"}},
+				{"молко", []string{"Купить молоко"}},
+				{"хлеп", []string{"Купить молоко"}},
+				{"купит хлеп", []string{"Купить молоко"}},
+				{"нарядит ёлку", []string{"Ёлка"}},
+				// В коротких словах опечатки не допускаются
+				{"кот", []string{}},
+				{"шоколад", []string{}},
+				{"молоко сыр", []string{}},
+			}
+			for _, v := range tbl {
+				assert.Equal(t, v.titles, searchTitles(t, repo, v.query), v.query)
+			}
+
+			// Точные совпадения выше совпадений с опечатками
+			assert.Equal(t, []string{"Полить цветы", "Пилить дрова", "Палить костер"}, searchTitles(t, repo, "полить"))
+
+			tasks, err := repo.SearchTask("хлеп")
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, "и <mark>хлеб</mark>", tasks[0].Snippet)
+			tasks, err = repo.SearchTask("малоко")
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, "Купить <mark>молоко</mark>", tasks[0].Snippet)
+
+			// Кандидаты берутся из индекса, который следует за изменениями задач
+			tasks, err = repo.SearchTask("полить")
+			require.NoError(t, err)
+			task := tasks[0]
+			task.Title = "Полить кактус"
+			require.NoError(t, repo.UpdateTask(task))
+			assert.Equal(t, []string{"Полить кактус"}, searchTitles(t, repo, "каткус"))
+			assert.Equal(t, []string{"Полить кактус"}, searchTitles(t, repo, "кактуз"))
+			assert.Equal(t, []string{}, searchTitles(t, repo, "цветы"))
+			require.NoError(t, repo.DeleteTask(task.ID))
+			assert.Equal(t, []string{}, searchTitles(t, repo, "каткус"))
+		})
+	}
+}
+
+// Нечеткий поиск не ограничен первыми задачами списка.
+func TestFuzzySearchManyTasks(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryRepo(todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC)))
+	for i := 0; i < 10001; i++ {
+		_, err := repo.Store.Insert(todo.Task{Date: mustDate(t, "20240126"), Title: "Задача", Comment: "без слова"})
+		require.NoError(t, err)
+	}
+	_, err := repo.Store.Insert(todo.Task{Date: mustDate(t, "20251231"), Title: "Купить молоко"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Купить молоко"}, searchTitles(t, repo, "малоко"))
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Выполняет запрос к серверу ts и возвращает тело ответа.
+func serverRequest(t *testing.T, ts *httptest.Server, method, path string, values map[string]any) []byte {
+	var data []byte
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		require.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, ts.URL+"/"+path, bytes.NewBuffer(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return body
+}
+
+// Серверы с репозиторием в памяти и своими часами независимы друг от друга и от основной БД тестов.
+func TestInMemoryServer(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		name string
+		now  time.Time
+		date string
+		next string
+	}{
+		{"january", time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC), "20240126", "20240127"},
+		{"june", time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), "20240615", "20240617"},
+	}
+	for _, v := range tbl {
+		t.Run(v.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := startServer(repository.NewMemoryRepo(todo.FixedClock(v.now)), todo.FixedClock(v.now))
+			defer ts.Close()
+
+			// без параметра now дата считается от часов сервера
+			body := serverRequest(t, ts, http.MethodGet, "api/nextdate?date=20240101&repeat=d+2", nil)
+			assert.Equal(t, v.next, string(body))
+
+			var created map[string]any
+			require.NoError(t, json.Unmarshal(serverRequest(t, ts, http.MethodPost, "api/task",
+				map[string]any{"date": "20240120", "title": "Созвон"}), &created))
+			id, ok := created["id"].(string)
+			require.True(t, ok, "ожидается id задачи, получено %v", created)
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(serverRequest(t, ts, http.MethodGet, "api/task?id="+id, nil), &got))
+			assert.Equal(t, v.date, got["date"])
+
+			var list struct {
+				Tasks []map[string]any `json:"tasks"`
+			}
+			require.NoError(t, json.Unmarshal(serverRequest(t, ts, http.MethodGet, "api/tasks", nil), &list))
+			require.Len(t, list.Tasks, 1)
+			assert.Equal(t, id, list.Tasks[0]["id"])
+		})
+	}
+}
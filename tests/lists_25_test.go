@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearches(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "lists.db"),
+		"memory": "",
+	}
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, err := repository.Open(name, dsn)
+			require.NoError(t, err)
+			defer repo.Store.Close()
+			repo.Clock = todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+
+			for _, v := range []todo.Task{
+				{Date: mustDate(t, "20240126"), Title: "Купить молоко", Comment: "и хлеб"},
+				{Date: mustDate(t, "20240127"), Title: "Оплатить интернет", Repeat: "m 27"},
+				{Date: mustDate(t, "20240128"), Title: "Купить цветы"},
+			} {
+				_, err := repo.AddTask(v)
+				require.NoError(t, err)
+			}
+
+			lists, err := repo.SavedSearches()
+			require.NoError(t, err)
+			assert.Empty(t, lists)
+
+			s, err := repo.AddSavedSearch(repository.SavedSearch{Name: " Покупки ", Query: "title:купить"})
+			require.NoError(t, err)
+			assert.Equal(t, "Покупки", s.Name)
+			_, err = repo.AddSavedSearch(repository.SavedSearch{Name: "Сегодня", Query: "today"})
+			require.NoError(t, err)
+			_, err = repo.AddSavedSearch(repository.SavedSearch{Name: "Покупки", Query: "молоко"})
+			assert.ErrorIs(t, err, repository.ErrSearchExists)
+
+			for _, v := range []repository.SavedSearch{
+				{Name: "", Query: "молоко"},
+				{Name: "a/b", Query: "молоко"},
+				{Name: "Пустой", Query: "  "},
+			} {
+				_, err := repo.AddSavedSearch(v)
+				assert.Error(t, err, v.Name)
+			}
+			for _, v := range []string{"repeat:sometimes", "31.10.2026-15.10.2026"} {
+				_, err := repo.AddSavedSearch(repository.SavedSearch{Name: "Ошибка", Query: v})
+				var queryErr *repository.QueryError
+				assert.True(t, errors.As(err, &queryErr), v)
+			}
+
+			lists, err = repo.SavedSearches()
+			require.NoError(t, err)
+			assert.Equal(t, []repository.SavedSearch{
+				{Name: "Покупки", Query: "title:купить"},
+				{Name: "Сегодня", Query: "today"},
+			}, lists)
+
+			// Результаты ищутся заново при каждом обращении
+			tasks, err := repo.SavedSearchTasks("Покупки")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Купить молоко", "Купить цветы"}, taskTitles(tasks))
+			_, err = repo.AddTask(todo.Task{Date: mustDate(t, "20240129"), Title: "Купить хлеб"})
+			require.NoError(t, err)
+			tasks, err = repo.SavedSearchTasks("Покупки")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Купить молоко", "Купить цветы", "Купить хлеб"}, taskTitles(tasks))
+
+			tasks, err = repo.SavedSearchTasks("Сегодня")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Купить молоко"}, taskTitles(tasks))
+			repo.Clock = todo.FixedClock(time.Date(2024, 1, 27, 10, 0, 0, 0, time.UTC))
+			tasks, err = repo.SavedSearchTasks("Сегодня")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Оплатить интернет"}, taskTitles(tasks))
+
+			s, err = repo.UpdateSavedSearch(repository.SavedSearch{Name: "Покупки", Query: "купить NOT хлеб"})
+			require.NoError(t, err)
+			tasks, err = repo.SavedSearchTasks(s.Name)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Купить цветы"}, taskTitles(tasks))
+			_, err = repo.UpdateSavedSearch(repository.SavedSearch{Name: "Покупки", Query: "("})
+			assert.Error(t, err)
+			s, err = repo.GetSavedSearch("Покупки")
+			require.NoError(t, err)
+			assert.Equal(t, "купить NOT хлеб", s.Query)
+
+			require.NoError(t, repo.DeleteSavedSearch("Покупки"))
+			assert.ErrorIs(t, repo.DeleteSavedSearch("Покупки"), repository.ErrSearchNotFound)
+			_, err = repo.GetSavedSearch("Покупки")
+			assert.ErrorIs(t, err, repository.ErrSearchNotFound)
+			_, err = repo.SavedSearchTasks("Покупки")
+			assert.ErrorIs(t, err, repository.ErrSearchNotFound)
+			_, err = repo.UpdateSavedSearch(repository.SavedSearch{Name: "Покупки", Query: "молоко"})
+			assert.ErrorIs(t, err, repository.ErrSearchNotFound)
+		})
+	}
+}
+
+func TestSavedSearchesAPI(t *testing.T) {
+	t.Parallel()
+
+	clock := todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+	repo := repository.NewMemoryRepo(clock)
+	for _, v := range []todo.Task{
+		{Date: mustDate(t, "20240126"), Title: "Купить молоко"},
+		{Date: mustDate(t, "20240127"), Title: "Зарядка", Repeat: "d 1"},
+	} {
+		_, err := repo.AddTask(v)
+		require.NoError(t, err)
+	}
+	ts := startServer(repo, clock)
+	defer ts.Close()
+
+	var m map[string]any
+	body := serverRequest(t, ts, http.MethodPost, "api/lists", map[string]any{"name": "Мои покупки", "query": "купить"})
+	require.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, map[string]any{"name": "Мои покупки", "query": "купить"}, m)
+
+	body = serverRequest(t, ts, http.MethodPost, "api/lists", map[string]any{"name": "Мои покупки", "query": "молоко"})
+	require.NoError(t, json.Unmarshal(body, &m))
+	assert.Contains(t, m["error"], "уже есть")
+	body = serverRequest(t, ts, http.MethodPost, "api/lists", map[string]any{"name": "Ошибка", "query": "repeat:sometimes"})
+	m = nil
+	require.NoError(t, json.Unmarshal(body, &m))
+	assert.Contains(t, m["error"], "repeat:sometimes")
+
+	serverRequest(t, ts, http.MethodPost, "api/lists", map[string]any{"name": "Повторы", "query": "repeat:any"})
+	var lists map[string][]map[string]string
+	body = serverRequest(t, ts, http.MethodGet, "api/lists", nil)
+	require.NoError(t, json.Unmarshal(body, &lists))
+	assert.Equal(t, []map[string]string{
+		{"name": "Мои покупки", "query": "купить"},
+		{"name": "Повторы", "query": "repeat:any"},
+	}, lists["lists"])
+
+	path := "api/lists/" + url.PathEscape("Мои покупки")
+	var tasks map[string][]map[string]string
+	body = serverRequest(t, ts, http.MethodGet, path+"/tasks", nil)
+	require.NoError(t, json.Unmarshal(body, &tasks))
+	require.Len(t, tasks["tasks"], 1)
+	assert.Equal(t, "Купить молоко", tasks["tasks"][0]["title"])
+
+	body = serverRequest(t, ts, http.MethodPut, path, map[string]any{"query": "зарядка"})
+	m = nil
+	require.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, map[string]any{"name": "Мои покупки", "query": "зарядка"}, m)
+	tasks = nil
+	body = serverRequest(t, ts, http.MethodGet, path+"/tasks", nil)
+	require.NoError(t, json.Unmarshal(body, &tasks))
+	require.Len(t, tasks["tasks"], 1)
+	assert.Equal(t, "Зарядка", tasks["tasks"][0]["title"])
+	assert.NotEmpty(t, tasks["tasks"][0]["description"])
+
+	body = serverRequest(t, ts, http.MethodDelete, path, nil)
+	m = nil
+	require.NoError(t, json.Unmarshal(body, &m))
+	assert.Empty(t, m)
+	for _, v := range []string{path, path + "/tasks"} {
+		body = serverRequest(t, ts, http.MethodGet, v, nil)
+		m = nil
+		require.NoError(t, json.Unmarshal(body, &m))
+		assert.Contains(t, m["error"], "не найден", v)
+	}
+}
+
+// Возвращает заголовки задач по порядку.
+func taskTitles(tasks []todo.Task) []string {
+	titles := make([]string, len(tasks))
+	for i, v := range tasks {
+		titles[i] = v.Title
+	}
+	return titles
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"todo/repository"
+	"todo/server"
+	todo "todo/task"
+)
+
+// По умолчанию тесты запускают сервер в своем процессе с отдельной БД во временном каталоге,
+// поэтому не зависят от запущенного сервера и файла ../scheduler.db. Чтобы проверить
+// отдельно запущенный сервер (порт Port, БД DBFile), задайте TODO_TEST_SERVER=1.
+//
+// Общий сервер работает с SQLite, а не с repository.NewMemoryRepo: исходные тесты API
+// (db_2, addtask_4, tasks_5, task_6, task_7) читают таблицу scheduler из файла БД напрямую,
+// а tasks_5 очищает ее. Эти тесты выполняются последовательно, до параллельных тестов,
+// которые создают свои задачи и находят их только по id, либо запускают свои серверы
+// и хранилища (startServer, repository.Open).
+func TestMain(m *testing.M) {
+	if os.Getenv("TODO_TEST_SERVER") != "" {
+		os.Exit(m.Run())
+	}
+	os.Exit(runHermetic(m))
+}
+
+func runHermetic(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "todo-tests")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	dbFile := filepath.Join(dir, "scheduler.db")
+	repo, err := repository.Open("sqlite", dbFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer repo.Store.Close()
+
+	ts := startServer(repo, todo.SystemClock)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Setenv("TODO_PORT", u.Port())
+	os.Setenv("TODO_DBFILE", dbFile)
+	return m.Run()
+}
+
+// Запускает сервер с репозиторием repo и часами clock в текущем процессе на свободном
+// локальном порту (httptest.Server). Сервер закрывается вызовом Close.
+func startServer(repo repository.RepositoryProcesser, clock todo.Clock) *httptest.Server {
+	srv := server.NewSrvWith(repo, clock)
+	srv.WebDir = "../web"
+	return httptest.NewServer(srv.Routes())
+}
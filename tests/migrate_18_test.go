@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"todo/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	db := openDB(t)
+	defer db.Close()
+
+	var version int
+	err := db.Get(&version, `SELECT MAX(version) FROM schema_version`)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, version, 6)
+
+	var index string
+	err = db.Get(&index, `SELECT name FROM sqlite_master WHERE type = 'index' AND name = 'scheduler_date'`)
+	assert.NoError(t, err)
+}
+
+func TestMigrateLegacyDB(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "legacy.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// База первой версии планировщика: только таблица scheduler
+	_, err = db.Exec("CREATE TABLE scheduler (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT,  title TEXT, comment TEXT, repeat TEXT)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240126', 'Старая задача', '', 'd 1')")
+	assert.NoError(t, err)
+
+	pending, err := repository.PendingMigrations(db)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pending)
+
+	// Пробный прогон не меняет базу
+	dry, err := repository.Migrate(db, true)
+	assert.NoError(t, err)
+	assert.Len(t, dry, len(pending))
+	version, err := repository.SchemaVersion(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	applied, err := repository.Migrate(db, false)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(pending))
+	version, err = repository.SchemaVersion(db)
+	assert.NoError(t, err)
+	assert.Equal(t, pending[len(pending)-1].Version, version)
+
+	var title string
+	assert.NoError(t, db.QueryRow("SELECT title FROM scheduler WHERE date = '20240126'").Scan(&title))
+	assert.Equal(t, "Старая задача", title)
+	_, err = db.Exec("INSERT INTO repeat_state (task_id, relative) VALUES (1, 'completion')")
+	assert.NoError(t, err)
+
+	applied, err = repository.Migrate(db, false)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+// Миграции проверяются по схеме выбранного хранилища. PostgreSQL проверяется,
+// если в TODO_PG_DSN указана строка подключения к тестовой базе.
+func TestMigratorBackends(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "migrator.db"),
+	}
+	if dsn := os.Getenv("TODO_PG_DSN"); dsn != "" {
+		backends["postgres"] = dsn
+	}
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			m, err := repository.OpenMigrator(name, dsn)
+			require.NoError(t, err)
+			defer m.Close()
+
+			migrations := m.Migrations()
+			require.NotEmpty(t, migrations)
+			last := migrations[len(migrations)-1].Version
+
+			// База PostgreSQL общая для запусков и может быть уже обновлена
+			version, err := m.Version()
+			require.NoError(t, err)
+			pending, err := m.Pending()
+			require.NoError(t, err)
+			assert.Len(t, pending, last-version)
+
+			dry, err := m.Migrate(true)
+			require.NoError(t, err)
+			assert.Len(t, dry, len(pending))
+			after, err := m.Version()
+			require.NoError(t, err)
+			assert.Equal(t, version, after)
+
+			applied, err := m.Migrate(false)
+			require.NoError(t, err)
+			assert.Len(t, applied, len(pending))
+			version, err = m.Version()
+			require.NoError(t, err)
+			assert.Equal(t, last, version)
+			pending, err = m.Pending()
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+		})
+	}
+
+	_, err := repository.OpenMigrator("memory", "")
+	assert.Error(t, err)
+	_, err = repository.OpenMigrator("nosuch", "")
+	assert.Error(t, err)
+}
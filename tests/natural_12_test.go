@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parsedRepeat struct {
+	Repeat      string `json:"repeat"`
+	RRule       string `json:"rrule"`
+	Description string `json:"description"`
+	Error       string `json:"error"`
+}
+
+func parseRepeat(t *testing.T, text string) parsedRepeat {
+	body, err := requestJSON("api/repeat?text="+url.QueryEscape(text), nil, http.MethodGet)
+	assert.NoError(t, err)
+	var p parsedRepeat
+	assert.NoError(t, json.Unmarshal(body, &p))
+	return p
+}
+
+func TestParseNaturalRepeat(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		text string
+		want string
+	}{
+		{"every day", "d 1"},
+		{"every 3 days", "d 3"},
+		{"через день", "d 2"},
+		{"каждый понедельник", "w 1"},
+		{"every 2 weeks on mon, wed", "w 1,3 /2"},
+		{"раз в 2 недели по пн и ср", "w 1,3 /2"},
+		{"every weekday", "w 1,2,3,4,5"},
+		{"по выходным", "w 0,6"},
+		{"every last friday", "m -1:5"},
+		{"каждую последнюю пятницу", "m -1:5"},
+		{"first and third monday of the month", "m 1:1,3:1"},
+		{"every month on the 1st and 15th", "m 1,15"},
+		{"каждый месяц 15-го числа", "m 15"},
+		{"последний день месяца", "m -1"},
+		{"every 3 months on the second tuesday", "m 2:2 /3"},
+		{"yearly", "y"},
+		{"yearly on dec 25", "y 12-25"},
+		{"ежегодно 25 декабря", "y 12-25"},
+		{"every other week", "RRULE:FREQ=WEEKLY;INTERVAL=2"},
+		{"w 7,1", "w 0,1"},
+	}
+	for _, v := range tbl {
+		p := parseRepeat(t, v.text)
+		assert.Empty(t, p.Error, "Неожиданная ошибка для %q", v.text)
+		assert.Equal(t, v.want, p.Repeat, "Неверное правило для %q", v.text)
+	}
+
+	p := parseRepeat(t, "every 2 weeks on mon, wed")
+	assert.Equal(t, "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", p.RRule)
+	assert.Equal(t, "раз в 2 недели по понедельникам и средам", p.Description)
+
+	for _, text := range []string{"", "ooops", "w", "every", "every 2 fortnights", "every tuesday 2nd", "daily on the 5th"} {
+		assert.NotEmpty(t, parseRepeat(t, text).Error, "Ожидается ошибка для %q", text)
+	}
+}
+
+func TestAddTaskNaturalRepeat(t *testing.T) {
+	t.Parallel()
+
+	id := addTaskJSON(t, map[string]any{
+		"date":   time.Now().Format(`20060102`),
+		"title":  "Отчет",
+		"repeat": "every last friday",
+	})
+	m := getTask(t, id)
+	assert.Equal(t, "m -1:5", m["repeat"])
+	assert.Equal(t, "каждый месяц в последнюю пятницу", m["description"])
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type series struct {
+	Description string   `json:"description"`
+	Dates       []string `json:"dates"`
+	Error       string   `json:"error"`
+}
+
+func getSeries(t *testing.T, params url.Values) series {
+	body, err := requestJSON("api/nextdates?"+params.Encode(), nil, http.MethodGet)
+	assert.NoError(t, err)
+	var s series
+	assert.NoError(t, json.Unmarshal(body, &s))
+	return s
+}
+
+func TestNextDates(t *testing.T) {
+	t.Parallel()
+
+	s := getSeries(t, url.Values{
+		"now": {"20240126"}, "date": {"20240101"}, "repeat": {"m 2:2"}, "n": {"3"},
+	})
+	assert.Empty(t, s.Error)
+	assert.Equal(t, []string{"20240213", "20240312", "20240409"}, s.Dates)
+	assert.Equal(t, "каждый месяц во второй вторник", s.Description)
+
+	s = getSeries(t, url.Values{
+		"date": {"20240101"}, "repeat": {"w 1,5 /2"}, "from": {"20240101"}, "to": {"20240131"},
+	})
+	assert.Empty(t, s.Error)
+	assert.Equal(t, []string{"20240101", "20240105", "20240115", "20240119", "20240129"}, s.Dates)
+	assert.Equal(t, "раз в 2 недели по понедельникам и пятницам", s.Description)
+
+	s = getSeries(t, url.Values{
+		"now": {"20240126"}, "date": {"20240126"}, "repeat": {"d 1"}, "count": {"3"},
+	})
+	assert.Equal(t, []string{"20240127", "20240128"}, s.Dates)
+	assert.Equal(t, "каждый день 3 раза", s.Description)
+
+	s = getSeries(t, url.Values{
+		"now": {"20240126"}, "date": {"20240101"}, "repeat": {"RRULE:FREQ=DAILY;UNTIL=20240128"},
+	})
+	assert.Equal(t, []string{"20240127", "20240128"}, s.Dates)
+	assert.Equal(t, "каждый день до 28.01.2024", s.Description)
+
+	s = getSeries(t, url.Values{"date": {"20240101"}, "repeat": {"ooops"}})
+	assert.NotEmpty(t, s.Error)
+
+	s = getSeries(t, url.Values{"date": {"20240101"}, "repeat": {"d 1"}, "n": {"0"}})
+	assert.NotEmpty(t, s.Error)
+}
+
+// Широкий интервал from-to не перебирается целиком: возвращаются первые maxPreviewDates дат.
+func TestNextDatesWideRange(t *testing.T) {
+	t.Parallel()
+
+	first := time.Date(1, 1, 2, 0, 0, 0, 0, time.UTC)
+	s := getSeries(t, url.Values{
+		"date": {"00010102"}, "repeat": {"d 1"}, "from": {"00010101"}, "to": {"99991231"},
+	})
+	assert.Empty(t, s.Error)
+	if assert.Len(t, s.Dates, 1000) {
+		assert.Equal(t, first.Format(`20060102`), s.Dates[0])
+		assert.Equal(t, first.AddDate(0, 0, 999).Format(`20060102`), s.Dates[999])
+	}
+
+	s = getSeries(t, url.Values{
+		"date": {"20240101"}, "time": {"09:00"}, "repeat": {"h 1"}, "from": {"20240101"}, "to": {"99991231"},
+	})
+	assert.Empty(t, s.Error)
+	if assert.Len(t, s.Dates, 1000) {
+		assert.Equal(t, "20240101 09:00", s.Dates[0])
+		assert.Equal(t, "20240212 00:00", s.Dates[999])
+	}
+}
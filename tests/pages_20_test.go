@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Добавляет 25 задач: на 1-25 февраля 2024, каждая третья повторяется.
+func addPageTasks(t *testing.T, repo *repository.Repository) {
+	for i := 1; i <= 25; i++ {
+		v := todo.Task{Date: mustDate(t, fmt.Sprintf("202402%02d", i)), Title: fmt.Sprintf("Задача %02d", 26-i)}
+		if i%3 == 0 {
+			v.Repeat = "d 30"
+		}
+		_, err := repo.AddTask(v)
+		require.NoError(t, err)
+	}
+}
+
+// Проходит все страницы списка и возвращает заголовки задач.
+func allPages(t *testing.T, repo *repository.Repository, q repository.ListQuery) []string {
+	var titles []string
+	for i := 0; ; i++ {
+		require.Less(t, i, 30, "слишком много страниц")
+		page, err := repo.ListTasks(q)
+		require.NoError(t, err)
+		for _, v := range page.Tasks {
+			titles = append(titles, v.Title)
+		}
+		if page.NextCursor == "" {
+			assert.Len(t, titles, page.Total)
+			return titles
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func TestListTasksPages(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "pages.db"),
+		"memory": "",
+	}
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, err := repository.Open(name, dsn)
+			require.NoError(t, err)
+			defer repo.Store.Close()
+			repo.Clock = todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+			addPageTasks(t, repo)
+
+			// Без параметров - первые 10 задач, как в GetTaskList
+			page, err := repo.ListTasks(repository.ListQuery{})
+			require.NoError(t, err)
+			assert.Len(t, page.Tasks, repository.DefaultPageSize)
+			assert.Equal(t, 25, page.Total)
+			assert.NotEmpty(t, page.NextCursor)
+			list, err := repo.GetTaskList()
+			require.NoError(t, err)
+			assert.Equal(t, list, page.Tasks)
+
+			titles := allPages(t, repo, repository.ListQuery{Limit: 7})
+			require.Len(t, titles, 25)
+			assert.Equal(t, "Задача 25", titles[0])
+			assert.Equal(t, "Задача 01", titles[24])
+
+			titles = allPages(t, repo, repository.ListQuery{Limit: 4, Desc: true})
+			require.Len(t, titles, 25)
+			assert.Equal(t, "Задача 01", titles[0])
+
+			titles = allPages(t, repo, repository.ListQuery{Limit: 10, Sort: repository.SortTitle})
+			require.Len(t, titles, 25)
+			assert.Equal(t, "Задача 01", titles[0])
+			assert.Equal(t, "Задача 25", titles[24])
+
+			from, to := mustDate(t, "20240205"), mustDate(t, "20240214")
+			titles = allPages(t, repo, repository.ListQuery{Limit: 3, From: from, To: to})
+			assert.Equal(t, []string{"Задача 21", "Задача 20", "Задача 19", "Задача 18", "Задача 17",
+				"Задача 16", "Задача 15", "Задача 14", "Задача 13", "Задача 12"}, titles)
+
+			titles = allPages(t, repo, repository.ListQuery{Limit: 3, Repeat: repository.RepeatOnly})
+			assert.Len(t, titles, 8)
+			titles = allPages(t, repo, repository.ListQuery{Limit: 3, Repeat: repository.RepeatNone, To: to})
+			assert.Len(t, titles, 10)
+
+			// Курсор другого порядка сортировки и испорченный курсор
+			page, err = repo.ListTasks(repository.ListQuery{Limit: 5})
+			require.NoError(t, err)
+			_, err = repo.ListTasks(repository.ListQuery{Limit: 5, Cursor: page.NextCursor, Desc: true})
+			assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+			_, err = repo.ListTasks(repository.ListQuery{Cursor: "???"})
+			assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+			_, err = repo.ListTasks(repository.ListQuery{Sort: "comment"})
+			assert.Error(t, err)
+			_, err = repo.ListTasks(repository.ListQuery{Limit: repository.MaxPageSize + 1})
+			assert.Error(t, err)
+			_, err = repo.ListTasks(repository.ListQuery{From: to, To: from})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTasksPagesAPI(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryRepo(todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC)))
+	addPageTasks(t, repo)
+	ts := startServer(repo, repo.Clock)
+	defer ts.Close()
+
+	type page struct {
+		Tasks      []map[string]any `json:"tasks"`
+		NextCursor string           `json:"next_cursor"`
+		Total      int              `json:"total"`
+	}
+	getPage := func(params url.Values) page {
+		var p page
+		require.NoError(t, json.Unmarshal(serverRequest(t, ts, http.MethodGet, "api/tasks?"+params.Encode(), nil), &p))
+		return p
+	}
+
+	// Без параметров ответ прежний: 10 ближайших задач без метаданных
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(serverRequest(t, ts, http.MethodGet, "api/tasks", nil), &m))
+	assert.Len(t, m["tasks"], 10)
+	assert.NotContains(t, m, "total")
+
+	params := url.Values{"limit": {"10"}, "from": {"2024-02-03"}, "repeat": {"false"}, "order": {"desc"}}
+	var dates []string
+	for i := 0; i < 5; i++ {
+		p := getPage(params)
+		assert.Equal(t, 15, p.Total)
+		for _, v := range p.Tasks {
+			dates = append(dates, v["date"].(string))
+		}
+		if p.NextCursor == "" {
+			break
+		}
+		params.Set("cursor", p.NextCursor)
+	}
+	require.Len(t, dates, 15)
+	assert.Equal(t, "20240225", dates[0])
+	assert.Equal(t, "20240204", dates[14])
+
+	for _, v := range []string{"limit=0", "limit=abc", "from=31.02.2024", "repeat=maybe", "order=up", "sort=comment", "cursor=abc"} {
+		require.NoError(t, json.Unmarshal(serverRequest(t, ts, http.MethodGet, "api/tasks?"+v, nil), &m))
+		assert.NotEmpty(t, m["error"], v)
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchQuery(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "query.db"),
+		"memory": "",
+	}
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, err := repository.Open(name, dsn)
+			require.NoError(t, err)
+			defer repo.Store.Close()
+			repo.Clock = todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+
+			for _, v := range []todo.Task{
+				{Date: mustDate(t, "20240127"), Title: "Купить молоко", Comment: "и хлеб"},
+				{Date: mustDate(t, "20240201"), Title: "Оплатить интернет", Comment: "до 25 числа", Repeat: "m 25"},
+				{Date: mustDate(t, "20240126"), Title: "Зарядка", Comment: "утром", Repeat: "d 1"},
+				{Date: mustDate(t, "20240315"), Title: "День рождения мамы", Comment: "купить цветы", Repeat: "y"},
+				{Date: mustDate(t, "20240129"), Title: "Отчет", Comment: "еженедельный", Repeat: "w 1"},
+				{Date: mustDate(t, "20240128"), Title: "Полив", Comment: "", Repeat: "RRULE:FREQ=DAILY;INTERVAL=2"},
+			} {
+				_, err := repo.AddTask(v)
+				require.NoError(t, err)
+			}
+
+			tbl := []struct {
+				query  string
+				titles []string
+			}{
+				{"title:купить", []string{"Купить молоко"}},
+				{"comment:купить", []string{"День рождения мамы"}},
+				{"купить", []string{"Купить молоко", "День рождения мамы"}},
+				{"купить NOT title:молоко", []string{"День рождения мамы"}},
+				{"купить AND NOT title:молоко", []string{"День рождения мамы"}},
+				{"repeat:none", []string{"Купить молоко"}},
+				{"repeat:d", []string{"Зарядка", "Полив"}},
+				{"repeat:any after:31.01.2024", []string{"Оплатить интернет", "День рождения мамы"}},
+				{"before:2024-01-28", []string{"Зарядка", "Купить молоко"}},
+				{"on:20240129", []string{"Отчет"}},
+				{"repeat:w OR repeat:y", []string{"Отчет", "День рождения мамы"}},
+				{"(title:отчет OR title:полив) AND NOT repeat:w", []string{"Полив"}},
+				{"Title:ОТЧЕТ OR мамы", []string{"Отчет", "День рождения мамы"}},
+				{`comment:"купить цветы"`, []string{"День рождения мамы"}},
+				{`comment:"цветы купить"`, []string{}},
+				{"молоко OR хлеб OR утром", []string{"Купить молоко", "Зарядка"}},
+				{"18:00", []string{}},
+				{"- —", []string{}},
+			}
+			for _, v := range tbl {
+				assert.Equal(t, v.titles, searchTitles(t, repo, v.query), v.query)
+			}
+
+			tasks, err := repo.SearchTask("title:молоко OR comment:цветы")
+			require.NoError(t, err)
+			require.Len(t, tasks, 2)
+			assert.Equal(t, "Купить <mark>молоко</mark>", tasks[0].Snippet)
+			assert.Equal(t, "купить <mark>цветы</mark>", tasks[1].Snippet)
+		})
+	}
+}
+
+func TestSearchQueryErrors(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		query string
+		pos   int
+	}{
+		{`"молоко`, 1},
+		{`(молоко`, 1},
+		{`молоко)`, 7},
+		{`молоко OR`, 10},
+		{`NOT`, 4},
+		{`repeat:q`, 1},
+		{`молоко before:31.02.2024`, 8},
+		{`on:когда-нибудь`, 1},
+		{`title:`, 1},
+		{`title:"молоко`, 7},
+		{`comment:"…"`, 1},
+	}
+	for _, v := range tbl {
+		_, err := repository.ParseQuery(v.query, time.Now())
+		var queryErr *repository.QueryError
+		if assert.True(t, errors.As(err, &queryErr), v.query) {
+			assert.Equal(t, v.pos, queryErr.Pos, v.query)
+		}
+	}
+
+	repo := repository.NewMemoryRepo(nil)
+	ts := startServer(repo, repo.Clock)
+	defer ts.Close()
+	var m map[string]any
+	body := serverRequest(t, ts, http.MethodGet, "api/tasks?search="+url.QueryEscape("repeat:sometimes"), nil)
+	require.NoError(t, json.Unmarshal(body, &m))
+	assert.Contains(t, m["error"], "repeat:sometimes")
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Сверяет ответы /api/nextdate на 20240126 с ожидаемыми; пустое want - ожидается ошибка.
+func checkNextDates(t *testing.T, tbl []nextDate) {
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+func TestNextDateRRule(t *testing.T) {
+	t.Parallel()
+
+	tbl := []nextDate{
+		{"20240101", "RRULE:FREQ=DAILY;INTERVAL=7", "20240129"},
+		{"20240101", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "20240129"},
+		{"20240101", "RRULE:FREQ=WEEKLY;BYDAY=SA,SU", "20240127"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1;BYMONTH=2,3", "20240229"},
+		{"20200229", "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "20240229"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "20241128"},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240130", "20240127"},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240125T000000Z", ""},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240130T120000Z", ""},
+		{"20240101", "RRULE:FREQ=DAILY;INTERVAL=399", "20250203"},
+		{"20240101", "d 399", "20250203"},
+		{"20240101", "RRULE:FREQ=DAILY;INTERVAL=400", ""},
+		{"20240101", "RRULE:FREQ=WEEKLY;INTERVAL=53;BYDAY=MO", ""},
+		{"20240101", "RRULE:FREQ=MONTHLY;INTERVAL=13;BYMONTHDAY=1", ""},
+		{"20240101", "RRULE:FREQ=YEARLY;INTERVAL=101", ""},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=3", ""},
+		{"20240125", "RRULE:FREQ=DAILY;COUNT=3", "20240127"},
+		{"20240101", "RRULE:FREQ=HOURLY", ""},
+		{"20240101", "RRULE:INTERVAL=2", ""},
+		{"20240101", "RRULE:FREQ=WEEKLY;BYDAY=2MO", ""},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=3;UNTIL=20240130", ""},
+	}
+	checkNextDates(t, tbl)
+}
+
+func TestNextDateNthWeekday(t *testing.T) {
+	t.Parallel()
+
+	tbl := []nextDate{
+		{"20240101", "m 2:2", "20240213"},
+		{"20240101", "m -1:5", "20240223"},
+		{"20240101", "m 1:1,3:1", "20240205"},
+		{"20240101", "m 5:4", "20240229"},
+		{"20240101", "m 5:1", "20240129"},
+		{"20240101", "m 1:1 1,4,7,10", "20240401"},
+		{"20240101", "m -1:7 3", "20240331"},
+		{"20240101", "m 6:1", ""},
+		{"20240101", "m 2:8", ""},
+		{"20240101", "m 0:1", ""},
+		{"20240101", "m 15,2:2", ""},
+	}
+	checkNextDates(t, tbl)
+}
+
+func TestNextDateInterval(t *testing.T) {
+	t.Parallel()
+
+	tbl := []nextDate{
+		{"20240101", "w 1 /2", "20240129"},
+		{"20240103", "w 1 /2", "20240129"},
+		{"20240108", "w 1,5 /2", "20240205"},
+		{"20231225", "w 1 /3", "20240205"},
+		{"20240101", "m 1 /3", "20240401"},
+		{"20231115", "m 15 /2", "20240315"},
+		{"20240101", "m -1:5 /2", "20240329"},
+		{"20240101", "w 1 /0", ""},
+		{"20240101", "w 1 /53", ""},
+		{"20240101", "m 1 /13", ""},
+		{"20240101", "m 1 /x", ""},
+		{"20240101", "w /2", ""},
+	}
+	checkNextDates(t, tbl)
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchDates(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "dates.db"),
+		"memory": "",
+	}
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, err := repository.Open(name, dsn)
+			require.NoError(t, err)
+			defer repo.Store.Close()
+
+			// Задачи добавляются заранее, чтобы прошедшие даты не перенеслись на сегодня
+			repo.Clock = todo.FixedClock(time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC))
+			for _, v := range []todo.Task{
+				{Date: mustDate(t, "20260915"), Title: "Старая"},
+				{Date: mustDate(t, "20261013"), Title: "Вчерашняя"},
+				{Date: mustDate(t, "20261014"), Title: "Сегодняшняя"},
+				{Date: mustDate(t, "20261015"), Title: "Завтрашняя"},
+				{Date: mustDate(t, "20261018"), Title: "В воскресенье"},
+				{Date: mustDate(t, "20261020"), Title: "Следующая неделя"},
+				{Date: mustDate(t, "20261031"), Title: "Конец месяца"},
+				{Date: mustDate(t, "20261110"), Title: "Ноябрь"},
+				{Date: mustDate(t, "20270101"), Title: "Новый год"},
+			} {
+				_, err := repo.AddTask(v)
+				require.NoError(t, err)
+			}
+			// Среда, 14 октября 2026
+			repo.Clock = todo.FixedClock(time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC))
+
+			october := []string{"Вчерашняя", "Сегодняшняя", "Завтрашняя", "В воскресенье", "Следующая неделя", "Конец месяца"}
+			thisWeek := []string{"Вчерашняя", "Сегодняшняя", "Завтрашняя", "В воскресенье"}
+			tbl := []struct {
+				query  string
+				titles []string
+			}{
+				{"today", []string{"Сегодняшняя"}},
+				{"Сегодня", []string{"Сегодняшняя"}},
+				{"tomorrow", []string{"Завтрашняя"}},
+				{"завтра", []string{"Завтрашняя"}},
+				{"вчера", []string{"Вчерашняя"}},
+				{"this week", thisWeek},
+				{"на этой  неделе", thisWeek},
+				{"next week", []string{"Следующая неделя"}},
+				{"в этом месяце", october},
+				{"в следующем месяце", []string{"Ноябрь"}},
+				{"overdue", []string{"Старая", "Вчерашняя"}},
+				{"Просроченные", []string{"Старая", "Вчерашняя"}},
+
+				{"14.10.2026", []string{"Сегодняшняя"}},
+				{"2026-10-14", []string{"Сегодняшняя"}},
+				{"20261014", []string{"Сегодняшняя"}},
+				{"10.2026", october},
+				{"2026-11", []string{"Ноябрь"}},
+				{"2027", []string{"Новый год"}},
+				{"15.10.2026-31.10.2026", []string{"Завтрашняя", "В воскресенье", "Следующая неделя", "Конец месяца"}},
+				{"2026-10-15 .. 2026-10-20", []string{"Завтрашняя", "В воскресенье", "Следующая неделя"}},
+				{"09.2026 — 10.2026", append([]string{"Старая"}, october...)},
+				{"сегодня-завтра", []string{"Сегодняшняя", "Завтрашняя"}},
+
+				{"on:today", []string{"Сегодняшняя"}},
+				{`on:"на следующей неделе"`, []string{"Следующая неделя"}},
+				{"before:today", []string{"Старая", "Вчерашняя"}},
+				{"after:10.2026", []string{"Ноябрь", "Новый год"}},
+				{"on:overdue OR on:tomorrow", []string{"Старая", "Вчерашняя", "Завтрашняя"}},
+				{"before:2026", []string{}},
+				{"on:15.10.2026-31.10.2026 NOT title:конец", []string{"Завтрашняя", "В воскресенье", "Следующая неделя"}},
+			}
+			for _, v := range tbl {
+				assert.Equal(t, v.titles, searchTitles(t, repo, v.query), v.query)
+			}
+			assert.Len(t, searchTitles(t, repo, "2026"), 8)
+
+			// Относительные даты считаются в часовом поясе запроса
+			repo.Clock = todo.FixedClock(time.Date(2026, 10, 14, 22, 30, 0, 0, time.UTC))
+			loc, err := todo.LoadLocation("+03:00")
+			require.NoError(t, err)
+			tasks, err := repo.WithLocation(loc).SearchTask("today")
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, "Завтрашняя", tasks[0].Title)
+
+			for _, v := range []string{"31.10.2026-15.10.2026", `on:"31.10.2026-15.10.2026"`, "on:someday"} {
+				_, err := repo.SearchTask(v)
+				var queryErr *repository.QueryError
+				assert.True(t, errors.As(err, &queryErr), v)
+			}
+		})
+	}
+}
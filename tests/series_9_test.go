@@ -0,0 +1,275 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addTaskJSON(t *testing.T, values map[string]any) string {
+	ret, err := postJSON("api/task", values, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	id, ok := ret["id"].(string)
+	assert.True(t, ok)
+	return id
+}
+
+func getTask(t *testing.T, id string) map[string]any {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func doneTask(t *testing.T, id string) {
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestSeriesCount(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	id := addTaskJSON(t, map[string]any{
+		"date":   now.Format(`20060102`),
+		"title":  "Курс антибиотиков",
+		"repeat": "d 1",
+		"count":  2,
+	})
+
+	doneTask(t, id)
+	m := getTask(t, id)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), m["date"])
+	assert.Equal(t, float64(1), m["done"])
+
+	doneTask(t, id)
+	notFoundTask(t, id)
+}
+
+func TestSeriesUntil(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	id := addTaskJSON(t, map[string]any{
+		"date":   now.Format(`20060102`),
+		"title":  "Полив рассады",
+		"repeat": "d 2",
+		"until":  now.AddDate(0, 0, 3).Format(`20060102`),
+	})
+
+	doneTask(t, id)
+	m := getTask(t, id)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), m["date"])
+
+	doneTask(t, id)
+	notFoundTask(t, id)
+}
+
+func TestSeriesInvalid(t *testing.T) {
+	t.Parallel()
+
+	tbl := []map[string]any{
+		{"title": "Без повтора", "until": "20300101"},
+		{"title": "Без повтора", "count": 3},
+		{"title": "Плохая дата", "repeat": "d 1", "until": "01.01.2030"},
+		{"title": "Отрицательное количество", "repeat": "d 1", "count": -1},
+	}
+	for _, v := range tbl {
+		ret, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "Ожидается ошибка для задачи %v", v)
+	}
+}
+
+func TestSkipOccurrence(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	id := addTaskJSON(t, map[string]any{
+		"date":   day(0),
+		"title":  "Планерка",
+		"repeat": "d 1",
+		"count":  3,
+	})
+
+	ret, err := postJSON("api/task/exdate?id="+id+"&date="+day(2), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	doneTask(t, id)
+	m := getTask(t, id)
+	assert.Equal(t, day(1), m["date"])
+
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	m = getTask(t, id)
+	assert.Equal(t, day(3), m["date"])
+	assert.Equal(t, float64(1), m["done"])
+	assert.Equal(t, []any{day(1), day(2)}, m["exclude"])
+
+	ret, err = postJSON("api/task/exdate?id="+id+"&date="+day(1), nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	m = getTask(t, id)
+	assert.Equal(t, []any{day(2)}, m["exclude"])
+
+	ret, err = postJSON("api/task/exdate?id="+id+"&date=oops", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
+
+func TestMissedPolicy(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	cleanup := func(ids ...string) {
+		for _, id := range ids {
+			_, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+			assert.NoError(t, err)
+		}
+	}
+
+	id := addTaskJSON(t, map[string]any{
+		"date":   day(-3),
+		"title":  "Принять лекарство",
+		"repeat": "d 1",
+		"missed": "step",
+	})
+	assert.Equal(t, day(-3), getTask(t, id)["date"])
+	doneTask(t, id)
+	assert.Equal(t, day(-2), getTask(t, id)["date"])
+	assert.Equal(t, "step", getTask(t, id)["missed"])
+
+	// PUT заменяет задачу целиком: без поля missed политика сбрасывается,
+	// а счетчик выполнений остается прежним, даже если клиент его передал
+	ret, err := postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   day(-2),
+		"title":  "Принять лекарство утром",
+		"repeat": "d 1",
+		"done":   100,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	m := getTask(t, id)
+	assert.Nil(t, m["missed"])
+	assert.Equal(t, float64(1), m["done"])
+
+	// Повторение снимается вместе с политикой и условиями окончания серии
+	ret, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   day(-2),
+		"title":  "Принять лекарство",
+		"repeat": "d 1",
+		"missed": "step",
+		"until":  day(30),
+		"count":  10,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	ret, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   day(-2),
+		"title":  "Принять лекарство",
+		"repeat": "",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	m = getTask(t, id)
+	assert.Equal(t, "", m["repeat"])
+	assert.Nil(t, m["missed"])
+	assert.Nil(t, m["until"])
+	assert.Nil(t, m["count"])
+	cleanup(id)
+
+	before := len(getTasks(t, url.QueryEscape("Полить цветы")))
+	id = addTaskJSON(t, map[string]any{
+		"date":   day(-4),
+		"title":  "Полить цветы",
+		"repeat": "d 2",
+		"missed": "overdue",
+	})
+	assert.Equal(t, day(2), getTask(t, id)["date"])
+	overdue := getTasks(t, url.QueryEscape("Полить цветы"))
+	assert.Equal(t, before+4, len(overdue))
+	for _, v := range overdue {
+		if v["id"] != id {
+			assert.Equal(t, "", v["repeat"])
+			cleanup(v["id"])
+		}
+	}
+	cleanup(id)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":  "Неизвестная политика",
+		"repeat": "d 1",
+		"missed": "never",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
+
+func TestRelativeCompletion(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+
+	tbl := []struct {
+		relative string
+		want     string
+	}{
+		{"", day(1)},
+		{"schedule", day(1)},
+		{"completion", day(3)},
+	}
+	for _, v := range tbl {
+		id := addTaskJSON(t, map[string]any{
+			"date":     day(-2),
+			"title":    "Полить цветы",
+			"repeat":   "d 3",
+			"missed":   "step",
+			"relative": v.relative,
+		})
+		doneTask(t, id)
+		m := getTask(t, id)
+		assert.Equal(t, v.want, m["date"], "Режим %q", v.relative)
+		if v.relative != "" {
+			assert.Equal(t, v.relative, m["relative"])
+		}
+
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	for _, v := range []map[string]any{
+		{"title": "Неизвестный режим", "repeat": "d 1", "relative": "sometimes"},
+		{"title": "Без повтора", "relative": "completion"},
+	} {
+		ret, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "Ожидается ошибка для задачи %v", v)
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo/repository"
+	todo "todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Проверяет, что все хранилища ведут себя одинаково. PostgreSQL проверяется,
+// если в TODO_PG_DSN указана строка подключения к пустой тестовой базе.
+func TestStorageConformance(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]string{
+		"sqlite": filepath.Join(t.TempDir(), "conformance.db"),
+		"memory": "",
+	}
+	if dsn := os.Getenv("TODO_PG_DSN"); dsn != "" {
+		backends["postgres"] = dsn
+	}
+	assert.Subset(t, repository.Backends(), []string{"sqlite", "memory", "postgres"})
+
+	for name, dsn := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, err := repository.Open(name, dsn)
+			require.NoError(t, err)
+			defer repo.Store.Close()
+			repo.Clock = todo.FixedClock(time.Date(2024, 1, 26, 10, 0, 0, 0, time.UTC))
+
+			// База PostgreSQL общая для запусков, поэтому начинаем с пустого хранилища
+			tasks, err := repo.Store.List(1000)
+			require.NoError(t, err)
+			for _, v := range tasks {
+				require.NoError(t, repo.DeleteTask(v.ID))
+			}
+
+			conformance(t, repo)
+		})
+	}
+}
+
+func mustDate(t *testing.T, s string) todo.Date {
+	d, err := todo.ParseDate(s)
+	require.NoError(t, err)
+	return d
+}
+
+func conformance(t *testing.T, repo *repository.Repository) {
+	// Дата в прошлом переносится на сегодня, время сохраняется
+	id, err := repo.AddTask(todo.Task{Date: mustDate(t, "20240120"), Time: "09:30", Title: "Позвонить", Comment: "маме"})
+	require.NoError(t, err)
+	got, err := repo.GetTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, "20240126", got.Date.String())
+	assert.Equal(t, "09:30", got.Time)
+	assert.Equal(t, "Позвонить", got.Title)
+	assert.Equal(t, "маме", got.Comment)
+
+	// Состояние серии повторений
+	series, err := repo.AddTask(todo.Task{Date: mustDate(t, "20240126"), Title: "Зарядка", Repeat: "d 2",
+		Count: 3, Missed: todo.MissedStep, Exclude: []todo.Date{mustDate(t, "20240130")}})
+	require.NoError(t, err)
+	got, err = repo.GetTask(series)
+	require.NoError(t, err)
+	assert.Equal(t, "d 2", got.Repeat)
+	assert.Equal(t, 3, got.Count)
+	assert.Equal(t, todo.MissedStep, got.Missed)
+	assert.Equal(t, []todo.Date{mustDate(t, "20240130")}, got.Exclude)
+	assert.Equal(t, "20240126", got.Start.String())
+
+	require.NoError(t, repo.DoneTask(series))
+	got, err = repo.GetTask(series)
+	require.NoError(t, err)
+	assert.Equal(t, "20240128", got.Date.String())
+	assert.Equal(t, 1, got.Done)
+
+	// Исключенная дата пропускается, пропуск повторения не увеличивает счетчик
+	require.NoError(t, repo.SkipTask(series))
+	got, err = repo.GetTask(series)
+	require.NoError(t, err)
+	assert.Equal(t, "20240201", got.Date.String())
+	assert.Equal(t, 1, got.Done)
+	assert.Equal(t, []todo.Date{mustDate(t, "20240128"), mustDate(t, "20240130")}, got.Exclude)
+	require.NoError(t, repo.IncludeDate(series, mustDate(t, "20240130")))
+	got, err = repo.GetTask(series)
+	require.NoError(t, err)
+	assert.Equal(t, []todo.Date{mustDate(t, "20240128")}, got.Exclude)
+	assert.Error(t, repo.IncludeDate(series, mustDate(t, "20240130")))
+
+	// Пропущенные повторения становятся просроченными задачами
+	overdue, err := repo.AddTask(todo.Task{Date: mustDate(t, "20240122"), Title: "Полив", Repeat: "d 2", Missed: todo.MissedOverdue})
+	require.NoError(t, err)
+	got, err = repo.GetTask(overdue)
+	require.NoError(t, err)
+	assert.Equal(t, "20240128", got.Date.String())
+	missed, err := repo.SearchTask("Полив")
+	require.NoError(t, err)
+	var dates []string
+	for _, v := range missed {
+		dates = append(dates, v.Date.String())
+		if v.ID != overdue {
+			assert.Empty(t, v.Repeat)
+		}
+	}
+	assert.Equal(t, []string{"20240122", "20240124", "20240126", "20240128"}, dates)
+
+	// Обновление
+	got, err = repo.GetTask(id)
+	require.NoError(t, err)
+	got.Title, got.Time = "Позвонить бабушке", ""
+	require.NoError(t, repo.UpdateTask(got))
+	got, err = repo.GetTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, "Позвонить бабушке", got.Title)
+	assert.Empty(t, got.Time)
+	assert.Error(t, repo.UpdateTask(todo.Task{ID: "999999", Date: mustDate(t, "20240126"), Title: "Нет такой"}))
+
+	// Список упорядочен по дате и времени
+	_, err = repo.AddTask(todo.Task{Date: mustDate(t, "20240126"), Time: "08:00", Title: "Завтрак"})
+	require.NoError(t, err)
+	list, err := repo.GetTaskList()
+	require.NoError(t, err)
+	require.Len(t, list, 7)
+	for i := 1; i < len(list); i++ {
+		prev, cur := list[i-1].Date.String()+list[i-1].Time, list[i].Date.String()+list[i].Time
+		assert.LessOrEqual(t, prev, cur)
+	}
+
+	// Поиск по дате и по подстроке
+	found, err := repo.SearchTask("26.01.2024")
+	require.NoError(t, err)
+	assert.Len(t, found, 3)
+	found, err = repo.SearchTask("бабушк")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, id, found[0].ID)
+	found, err = repo.SearchTask("нет такой задачи")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	// Поиск одинаков во всех хранилищах: слова ищутся как начала слов без учета регистра,
+	// диакритики и различия "ё" и "е", а после точных совпадений идут совпадения с опечатками
+	cafe, err := repo.AddTask(todo.Task{Date: mustDate(t, "20240126"), Title: "Встреча в Café", Comment: "у ёлки"})
+	require.NoError(t, err)
+	for query, want := range map[string][]string{
+		"ушке":           nil,
+		"cafe":           {cafe},
+		"CAFÉ":           {cafe},
+		"title:cafe":     {cafe},
+		"comment:cafe":   nil,
+		"елки":           {cafe},
+		`"у елки"`:       {cafe},
+		`"встреча cafe"`: nil,
+		"бобушке":        {id},
+		"позвонить баб":  {id},
+	} {
+		found, err = repo.SearchTask(query)
+		require.NoError(t, err, query)
+		var ids []string
+		for _, v := range found {
+			ids = append(ids, v.ID)
+		}
+		assert.Equal(t, want, ids, query)
+	}
+	require.NoError(t, repo.DeleteTask(cafe))
+
+	// Выполнение неповторяющейся задачи и последнего повторения удаляет задачу
+	require.NoError(t, repo.DoneTask(id))
+	_, err = repo.GetTask(id)
+	assert.Error(t, err)
+	require.NoError(t, repo.DoneTask(series))
+	require.NoError(t, repo.DoneTask(series))
+	_, err = repo.GetTask(series)
+	assert.Error(t, err)
+
+	// Удаление
+	require.NoError(t, repo.DeleteTask(overdue))
+	assert.Error(t, repo.DeleteTask(overdue))
+	_, err = repo.GetTask(overdue)
+	assert.Error(t, err)
+	_, err = repo.GetTask("")
+	assert.Error(t, err)
+	_, err = repo.GetTask("abc")
+	assert.Error(t, err)
+}
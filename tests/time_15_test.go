@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateTime(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		date, clock, repeat, now string
+		want                     string
+	}{
+		{"20240126", "09:30", "h 4", "20240126 10:00", "20240126 13:30"},
+		{"20240126", "23:30", "min 45", "20240126 23:50", "20240127 00:15"},
+		{"20240126", "22:00", "h 5", "20240127T10:00", "20240127 13:00"},
+		{"20240126", "09:30", "d 1", "20240126", "20240127 09:30"},
+		{"20240126", "", "d 1", "20240126", "20240127"},
+		{"20240126", "", "h 6", "20240126 07:00", "20240126 12:00"},
+		{"20240126", "", "h 0", "20240126", ""},
+		{"20240126", "", "h 169", "20240126", ""},
+		{"20240126", "", "min 1441", "20240126", ""},
+		{"20240126", "", "h 4 bd", "20240126", ""},
+		{"20240126", "25:00", "d 1", "20240126", ""},
+		{"20240126", "09:30", "RRULE:FREQ=HOURLY;INTERVAL=4", "20240126 10:00", "20240126 13:30"},
+		{"20240126", "23:30", "RRULE:FREQ=MINUTELY;INTERVAL=45", "20240126 23:50", "20240127 00:15"},
+		{"20240126", "22:00", "RRULE:FREQ=HOURLY;INTERVAL=5;UNTIL=20240126", "20240126 23:00", ""},
+		{"20240126", "", "RRULE:FREQ=HOURLY;INTERVAL=169", "20240126", ""},
+		{"20240126", "", "RRULE:FREQ=HOURLY;COUNT=3", "20240126", ""},
+		{"20240126", "", "RRULE:FREQ=MINUTELY;BYDAY=MO", "20240126", ""},
+		{"20240126", "", "RRULE:FREQ=HOURLY;BYDAY=MO", "20240126", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=%s&date=%s&time=%s&repeat=%s",
+			url.QueryEscape(v.now), v.date, url.QueryEscape(v.clock), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		if v.want == "" {
+			assert.Contains(t, next, "error", "Ожидается ошибка для %v", v)
+			continue
+		}
+		assert.Equal(t, v.want, next, "%v", v)
+	}
+}
+
+// RRULE правил h N и min N разбирается обратно в то же правило.
+func TestIntradayRRule(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		text, rrule string
+	}{
+		{"h 2", "RRULE:FREQ=HOURLY;INTERVAL=2"},
+		{"min 30", "RRULE:FREQ=MINUTELY;INTERVAL=30"},
+		{"every 4 hours", "RRULE:FREQ=HOURLY;INTERVAL=4"},
+		{"h 1", "RRULE:FREQ=HOURLY"},
+	}
+	for _, v := range tbl {
+		p := parseRepeat(t, v.text)
+		assert.Empty(t, p.Error, v.text)
+		assert.Equal(t, v.rrule, p.RRule, v.text)
+
+		back := parseRepeat(t, p.RRule)
+		assert.Empty(t, back.Error, p.RRule)
+		assert.Equal(t, p.RRule, back.Repeat, p.RRule)
+		assert.Equal(t, p.RRule, back.RRule, p.RRule)
+		assert.Equal(t, p.Description, back.Description, p.RRule)
+	}
+}
+
+func TestTaskTime(t *testing.T) {
+	t.Parallel()
+
+	today := time.Now().Format(`20060102`)
+	id := addTaskJSON(t, map[string]any{
+		"date":   today,
+		"time":   "08:00",
+		"title":  "Проветрить комнату",
+		"repeat": "every 4 hours",
+		"missed": "step",
+	})
+	m := getTask(t, id)
+	assert.Equal(t, "h 4", m["repeat"])
+	assert.Equal(t, "08:00", m["time"])
+	assert.Equal(t, "раз в 4 часа", m["description"])
+
+	// Задачи с одной датой упорядочены по времени
+	tasks := getTasks(t, "")
+	for i := 1; i < len(tasks); i++ {
+		prev, cur := tasks[i-1]["date"]+" "+tasks[i-1]["time"], tasks[i]["date"]+" "+tasks[i]["time"]
+		assert.LessOrEqual(t, prev, cur)
+	}
+
+	doneTask(t, id)
+	m = getTask(t, id)
+	assert.Equal(t, today, m["date"])
+	assert.Equal(t, "12:00", m["time"])
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":  today,
+		"time":  "9:61",
+		"title": "Неверное время",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
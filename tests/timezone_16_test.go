@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateTimezone(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		tz, now, date, clock, repeat string
+		want                         string
+	}{
+		// 20:00 UTC - в Токио уже следующий день
+		{"Asia/Tokyo", "2024-01-26T20:00:00Z", "20240126", "", "d 1", "20240128"},
+		{"UTC", "2024-01-26T20:00:00Z", "20240126", "", "d 1", "20240127"},
+		{"-05:00", "2024-01-27T03:00:00Z", "20240126", "", "d 1", "20240127"},
+		// Время now без пояса считается в поясе tz
+		{"Europe/Moscow", "20240126 23:30", "20240126", "", "d 1", "20240127"},
+		// Переход на летнее время: 02:00-03:00 10 марта в Нью-Йорке не существует
+		{"America/New_York", "20240310 01:45", "20240310", "00:30", "h 1", "20240310 03:30"},
+		{"America/New_York", "20240310 01:45", "20240309", "09:00", "d 1", "20240311 09:00"},
+		{"UTC", "20240310 01:45", "20240310", "00:30", "h 1", "20240310 02:30"},
+		// Переход на зимнее время: 01:00-02:00 3 ноября повторяется дважды
+		{"America/New_York", "2024-11-03T05:45:00Z", "20241103", "00:30", "h 1", "20241103 01:30"},
+		{"America/New_York", "2024-11-03T06:45:00Z", "20241103", "00:30", "h 1", "20241103 02:30"},
+		{"Mars/Olympus", "20240126", "20240126", "", "d 1", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?tz=%s&now=%s&date=%s&time=%s&repeat=%s", url.QueryEscape(v.tz),
+			url.QueryEscape(v.now), v.date, url.QueryEscape(v.clock), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		if v.want == "" {
+			assert.Contains(t, next, "error", "Ожидается ошибка для %v", v)
+			continue
+		}
+		assert.Equal(t, v.want, next, "%v", v)
+	}
+}
+
+func TestAddTaskTimezone(t *testing.T) {
+	t.Parallel()
+
+	// Между поясами 25 часов: дата в Паго-Паго всегда раньше, чем на Кирибати
+	east, err := time.LoadLocation("Pacific/Kiritimati")
+	assert.NoError(t, err)
+	west, err := time.LoadLocation("Pacific/Pago_Pago")
+	assert.NoError(t, err)
+	now := time.Now()
+	eastDay, westDay := now.In(east).Format(`20060102`), now.In(west).Format(`20060102`)
+
+	// Для пользователя в Паго-Паго дата на Кирибати еще не наступила
+	ret, err := postJSON("api/task?tz=Pacific/Pago_Pago", map[string]any{
+		"date":  eastDay,
+		"title": "Созвон с Кирибати",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	assert.Equal(t, eastDay, getTask(t, id)["date"])
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+
+	// Для пользователя на Кирибати сегодняшняя дата Паго-Паго уже прошла
+	ret, err = postJSON("api/task?tz=Pacific/Kiritimati", map[string]any{
+		"date":  westDay,
+		"title": "Созвон с Паго-Паго",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+	assert.Equal(t, eastDay, getTask(t, id)["date"])
+	ret, err = postJSON("api/task?tz=Mars/Olympus", map[string]any{
+		"id":    id,
+		"date":  eastDay,
+		"title": "Неизвестный пояс",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+
+	ret, err = postJSON("api/task?tz=Mars/Olympus", map[string]any{
+		"title": "Неизвестный пояс",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
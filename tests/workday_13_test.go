@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateWorkday(t *testing.T) {
+	t.Parallel()
+
+	tbl := []nextDate{
+		{"20240126", "d 1 bd", "20240129"},
+		{"20240122", "d 2 bd", "20240130"},
+		{"20240101", "m 3 bd+", "20240205"},
+		{"20240101", "m 25 bd-", "20240223"},
+		{"20240101", "m 27 bd-", "20240227"},
+		{"20240101", "m 27 bd", "20240227"},
+		{"20240101", "w 1 /2 bd", "20240129"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=3;X-WORKDAY=NEXT", "20240205"},
+		{"20240101", "w 6 bd", ""},
+		{"20240101", "d 1 bdx", ""},
+		{"20240101", " bd", ""},
+		{"20240101", "RRULE:FREQ=DAILY;X-WORKDAY=SOMETIMES", ""},
+	}
+	checkNextDates(t, tbl)
+}
+
+func TestParseWorkdayRepeat(t *testing.T) {
+	t.Parallel()
+
+	tbl := []struct {
+		text string
+		want string
+	}{
+		{"every business day", "d 1 bd"},
+		{"every 3 business days", "d 3 bd"},
+		{"по рабочим дням", "d 1 bd"},
+		{"first business day of the month", "m 1 bd+"},
+		{"последний рабочий день месяца", "m -1 bd-"},
+		{"RRULE:FREQ=MONTHLY;X-WORKDAY=prev;BYMONTHDAY=-1", "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1;X-WORKDAY=PREV"},
+	}
+	for _, v := range tbl {
+		p := parseRepeat(t, v.text)
+		assert.Empty(t, p.Error, "Неожиданная ошибка для %q", v.text)
+		assert.Equal(t, v.want, p.Repeat, "Неверное правило для %q", v.text)
+	}
+
+	p := parseRepeat(t, "m -1 bd-")
+	assert.Equal(t, "каждый месяц последнего числа с переносом на предыдущий рабочий день", p.Description)
+	assert.Equal(t, "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1;X-WORKDAY=PREV", p.RRule)
+	p = parseRepeat(t, "d 3 bd")
+	assert.Equal(t, "раз в 3 рабочих дня", p.Description)
+}
@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateYearly(t *testing.T) {
+	t.Parallel()
+
+	tbl := []nextDate{
+		{"20240101", "y 12-25", "20241225"},
+		{"20240101", "y 01-26,12-25", "20241225"},
+		{"20240101", "y 01-27", "20240127"},
+		{"20240101", "y 02-29", "20240229"},
+		{"20230301", "y 02-29 /2", "20250228"},
+		{"20200101", "y /2", "20260101"},
+		{"20240101", "y 03-31 /3", "20240331"},
+		{"20240101", "RRULE:FREQ=YEARLY;INTERVAL=2;X-BYMONTHDATE=02-29", "20240229"},
+		{"20240101", "y 04-31", ""},
+		{"20240101", "y 13-01", ""},
+		{"20240101", "y 1225", ""},
+		{"20240101", "y 12-25 /0", ""},
+		{"20240101", "y 12-25 /101", ""},
+		{"20240101", "RRULE:FREQ=MONTHLY;X-BYMONTHDATE=02-29", ""},
+	}
+	checkNextDates(t, tbl)
+}
+
+func TestParseYearlyRepeat(t *testing.T) {
+	t.Parallel()
+
+	p := parseRepeat(t, "y 12-25,3-31,12-25 /2")
+	assert.Empty(t, p.Error)
+	assert.Equal(t, "y 03-31,12-25 /2", p.Repeat)
+	assert.Equal(t, "RRULE:FREQ=YEARLY;INTERVAL=2;X-BYMONTHDATE=03-31,12-25", p.RRule)
+	assert.Equal(t, "раз в 2 года 31 марта и 25 декабря", p.Description)
+
+	p = parseRepeat(t, "every 2 years")
+	assert.Empty(t, p.Error)
+	assert.Equal(t, "y /2", p.Repeat)
+}